@@ -1,7 +1,13 @@
 package builder
 
 import (
+	"log/slog"
+
 	"github.com/mariotoffia/goannoy/distance/angular"
+	"github.com/mariotoffia/goannoy/distance/dotproduct"
+	"github.com/mariotoffia/goannoy/distance/euclidean"
+	"github.com/mariotoffia/goannoy/distance/hamming"
+	"github.com/mariotoffia/goannoy/distance/manhattan"
 	"github.com/mariotoffia/goannoy/index"
 	"github.com/mariotoffia/goannoy/index/memory"
 	"github.com/mariotoffia/goannoy/index/policy"
@@ -18,6 +24,10 @@ type AnnoyIndexBuilderImpl[TV interfaces.VectorType, TIX interfaces.IndexTypes]
 	indexMemoryAllocator interfaces.IndexAllocator
 	sorter               interfaces.Sorter[TV, TIX]
 	logVerbose           bool
+	fileHeader           bool
+	annoyCompatible      bool
+	logger               *slog.Logger
+	incremental          bool
 }
 
 // Index creates a new `AnnoyIndexBuilderImpl` instance.
@@ -44,6 +54,40 @@ func (bld *AnnoyIndexBuilderImpl[TV, TIX]) AngularDistance(vectorLength int) *An
 	return bld
 }
 
+func (bld *AnnoyIndexBuilderImpl[TV, TIX]) DotProductDistance(vectorLength int) *AnnoyIndexBuilderImpl[TV, TIX] {
+	bld.distance = dotproduct.Distance[TV](TIX(vectorLength))
+	return bld
+}
+
+func (bld *AnnoyIndexBuilderImpl[TV, TIX]) EuclideanDistance(vectorLength int) *AnnoyIndexBuilderImpl[TV, TIX] {
+	bld.distance = euclidean.Distance[TV](TIX(vectorLength))
+	return bld
+}
+
+func (bld *AnnoyIndexBuilderImpl[TV, TIX]) ManhattanDistance(vectorLength int) *AnnoyIndexBuilderImpl[TV, TIX] {
+	bld.distance = manhattan.Distance[TV](TIX(vectorLength))
+	return bld
+}
+
+// HammingDistance uses the hamming distance on packed bit vectors. The _vectorLength_ is the
+// number of `uint64` elements in the vector and hence _TV_ *must* be `uint64`.
+func (bld *AnnoyIndexBuilderImpl[TV, TIX]) HammingDistance(vectorLength int) *AnnoyIndexBuilderImpl[TV, TIX] {
+	d, ok := any(hamming.Distance(TIX(vectorLength))).(interfaces.Distance[TV, TIX])
+	if !ok {
+		panic("hamming distance requires uint64 as vector type")
+	}
+
+	bld.distance = d
+	return bld
+}
+
+// Distance uses a custom distance implementation, e.g. a metric that is not part of this
+// library. The _d_ decides the vector length of the index.
+func (bld *AnnoyIndexBuilderImpl[TV, TIX]) Distance(d interfaces.Distance[TV, TIX]) *AnnoyIndexBuilderImpl[TV, TIX] {
+	bld.distance = d
+	return bld
+}
+
 func (bld *AnnoyIndexBuilderImpl[TV, TIX]) UseMultiWorkerPolicy() *AnnoyIndexBuilderImpl[TV, TIX] {
 	bld.buildPolicy = policy.MultiWorker()
 	return bld
@@ -54,6 +98,14 @@ func (bld *AnnoyIndexBuilderImpl[TV, TIX]) SingleWorkerPolicy() *AnnoyIndexBuild
 	return bld
 }
 
+// OnDiskBuild will build the index directly into the file _fqFile_ instead of in memory. This
+// makes it possible to build indexes larger than the available memory. When the index is
+// saved to the same file, it only needs to be loaded.
+func (bld *AnnoyIndexBuilderImpl[TV, TIX]) OnDiskBuild(fqFile string) *AnnoyIndexBuilderImpl[TV, TIX] {
+	bld.allocator = memory.MmapIndexBuildAllocator(fqFile)
+	return bld
+}
+
 func (bld *AnnoyIndexBuilderImpl[TV, TIX]) MmapIndexAllocator() *AnnoyIndexBuilderImpl[TV, TIX] {
 	bld.indexMemoryAllocator = memory.MmapIndexAllocator()
 	return bld
@@ -74,6 +126,36 @@ func (bld *AnnoyIndexBuilderImpl[TV, TIX]) VerboseLogging() *AnnoyIndexBuilderIm
 	return bld
 }
 
+// Logger sets the _logger_ that receives build statistics, save and load events. The
+// node dumps are logged at `index.LevelTrace`. It takes precedence over `VerboseLogging`.
+func (bld *AnnoyIndexBuilderImpl[TV, TIX]) Logger(logger *slog.Logger) *AnnoyIndexBuilderImpl[TV, TIX] {
+	bld.logger = logger
+	return bld
+}
+
+// Incremental allows items to be added after the index has been built or loaded. Those are
+// searched using brute force until `Compact` is invoked. See `index.WithIncrementalInserts`.
+func (bld *AnnoyIndexBuilderImpl[TV, TIX]) Incremental() *AnnoyIndexBuilderImpl[TV, TIX] {
+	bld.incremental = true
+	return bld
+}
+
+// FileHeader makes the index write a self describing header when saved. The header is
+// validated when loaded so that a file is not loaded into a differently configured index.
+func (bld *AnnoyIndexBuilderImpl[TV, TIX]) FileHeader() *AnnoyIndexBuilderImpl[TV, TIX] {
+	bld.fileHeader = true
+	return bld
+}
+
+// AnnoyCompatible guarantees that the saved files can be loaded by Spotify Annoy and vice versa.
+// It requires `float32` vectors (`uint64` for hamming) and `uint32` indexes and can't be combined
+// with `FileHeader`, otherwise building, saving and loading fails. See `index.WithAnnoyCompatibility`
+// for more information.
+func (bld *AnnoyIndexBuilderImpl[TV, TIX]) AnnoyCompatible() *AnnoyIndexBuilderImpl[TV, TIX] {
+	bld.annoyCompatible = true
+	return bld
+}
+
 func (bld *AnnoyIndexBuilderImpl[TV, TIX]) Build() interfaces.AnnoyIndex[TV, TIX] {
 
 	if bld.buildPolicy == nil {
@@ -100,6 +182,24 @@ func (bld *AnnoyIndexBuilderImpl[TV, TIX]) Build() interfaces.AnnoyIndex[TV, TIX
 		}
 	}
 
+	var opts []index.Option[TV, TIX]
+
+	if bld.fileHeader {
+		opts = append(opts, index.WithFileHeader[TV, TIX]())
+	}
+
+	if bld.annoyCompatible {
+		opts = append(opts, index.WithAnnoyCompatibility[TV, TIX]())
+	}
+
+	if bld.incremental {
+		opts = append(opts, index.WithIncrementalInserts[TV, TIX]())
+	}
+
+	if bld.logger != nil {
+		opts = append(opts, index.WithLogger[TV, TIX](bld.logger))
+	}
+
 	return index.New(
 		bld.random,
 		bld.distance,
@@ -109,5 +209,6 @@ func (bld *AnnoyIndexBuilderImpl[TV, TIX]) Build() interfaces.AnnoyIndex[TV, TIX
 		bld.sorter,
 		bld.logVerbose,
 		bld.allocHint,
+		opts...,
 	)
 }
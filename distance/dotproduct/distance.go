@@ -15,7 +15,7 @@ type dotProductDistanceImpl[TV interfaces.VectorType, TIX interfaces.IndexTypes]
 	vectorLength   TIX
 }
 
-// Distance creates a new angular distance implementation.
+// Distance creates a new dot product distance implementation.
 func Distance[TV interfaces.VectorType, TIX interfaces.IndexTypes](
 	vectorLength TIX,
 ) *dotProductDistanceImpl[TV, TIX] {
@@ -81,12 +81,16 @@ func (dp *dotProductDistanceImpl[TV, TIX]) CreateSplit(
 		nv[z] = pv[z] - qv[z]
 	}
 
+	n.(*DotProductNodeImpl[TV, TIX]).dot_factor = p.dot_factor - q.dot_factor
+
 	dp.Normalize(n)
 }
 
+// Normalize will normalize the vector including the extra dimension held by the `dot_factor`.
 func (dp *dotProductDistanceImpl[TV, TIX]) Normalize(node interfaces.Node[TV, TIX]) {
 	raw := node.GetRawVector()
-	norm := TV(vector.GetNormUnsafe(raw, dp.vectorLength))
+	df := node.(*DotProductNodeImpl[TV, TIX]).dot_factor
+	norm := TV(math.Sqrt(float64(vector.DotUnsafe(raw, raw, dp.vectorLength) + df*df)))
 
 	if norm > 0 {
 		ptr := unsafe.Pointer(raw)
@@ -149,31 +153,9 @@ func (dp *dotProductDistanceImpl[TV, TIX]) PQInitialValue() TV {
 	return TV(math.Inf(1))
 }
 
+// Distance is the negated dot product, hence a larger dot product is a closer item.
 func (dp *dotProductDistanceImpl[TV, TIX]) Distance(x interfaces.Node[TV, TIX], y interfaces.Node[TV, TIX]) TV {
-	pp := x.GetNorm()
-	qq := y.GetNorm()
-	xv := x.GetRawVector()
-	yv := y.GetRawVector()
-
-	if pp == 0 {
-		pp = vector.DotUnsafe(xv, xv, dp.vectorLength)
-	}
-
-	if qq == 0 {
-		qq = vector.DotUnsafe(yv, yv, dp.vectorLength)
-	}
-
-	var ppqq TV
-
-	if pp != 0 {
-		ppqq = pp * qq
-	}
-
-	if ppqq > 0 {
-		pq := vector.DotUnsafe(xv, yv, dp.vectorLength)
-		return 2.0 - 2.0*pq/TV(math.Sqrt(float64(ppqq)))
-	}
-	return 2.0
+	return -vector.DotUnsafe(x.GetRawVector(), y.GetRawVector(), dp.vectorLength)
 }
 
 func (dp *dotProductDistanceImpl[TV, TIX]) PreProcess(nodes unsafe.Pointer, node_count TIX) {
@@ -227,5 +209,5 @@ func (dp *dotProductDistanceImpl[TV, TIX]) InitNode(node interfaces.Node[TV, TIX
 }
 
 func (dp *dotProductDistanceImpl[TV, TIX]) Name() string {
-	return "angular"
+	return "dot"
 }
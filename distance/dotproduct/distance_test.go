@@ -1,17 +1,18 @@
-package dotproduct
+package dotproduct_test
 
 import (
 	"math"
 	"testing"
 
 	"github.com/mariotoffia/goannoy/builder"
+	"github.com/mariotoffia/goannoy/distance/dotproduct"
 	"github.com/mariotoffia/goannoy/interfaces"
 	"github.com/stretchr/testify/assert"
 )
 
 func createIndex(vectorLength int) interfaces.AnnoyIndex[float32, uint32] {
 	return builder.Index[float32, uint32]().
-		AngularDistance(vectorLength).
+		DotProductDistance(vectorLength).
 		SingleWorkerPolicy().
 		Build()
 }
@@ -32,3 +33,46 @@ func TestGetDotProductDistance(t *testing.T) {
 	assert.True(t, zero < 0.00001)
 
 }
+
+func TestGetNnsByVectorPrefersLargestDotProduct(t *testing.T) {
+	idx := createIndex(2)
+	defer idx.Close()
+
+	idx.AddItem(0, []float32{1, 0})
+	idx.AddItem(1, []float32{3, 0})
+	idx.AddItem(2, []float32{0, 2})
+	idx.Build(10, -1)
+
+	ctx := idx.CreateContext()
+
+	result, distances := idx.GetNnsByVector([]float32{1, 0}, 3, -1, ctx)
+	assert.Equal(t, []uint32{1, 0, 2}, result)
+	assert.Equal(t, []float32{3, 1, 0}, distances)
+}
+
+func TestCustomDistance(t *testing.T) {
+	idx := builder.Index[float32, uint32]().
+		Distance(dotproduct.Distance[float32](uint32(2))).
+		SingleWorkerPolicy().
+		Build()
+
+	defer idx.Close()
+
+	assert.Equal(t, uint32(2), idx.VectorLength())
+}
+
+func TestGetNnsByVectorWithSplits(t *testing.T) {
+	idx := createIndex(2)
+	defer idx.Close()
+
+	for i := 0; i < 100; i++ {
+		idx.AddItem(uint32(i), []float32{float32(i), 1})
+	}
+
+	idx.Build(10, -1)
+
+	ctx := idx.CreateContext()
+
+	result, _ := idx.GetNnsByVector([]float32{1, 0}, 3, 1000, ctx)
+	assert.Equal(t, []uint32{99, 98, 97}, result)
+}
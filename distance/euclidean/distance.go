@@ -0,0 +1,183 @@
+package euclidean
+
+import (
+	"math"
+	"unsafe"
+
+	"github.com/mariotoffia/goannoy/distance"
+	"github.com/mariotoffia/goannoy/interfaces"
+	"github.com/mariotoffia/goannoy/vector"
+)
+
+type euclideanDistanceImpl[TV interfaces.VectorType, TIX interfaces.IndexTypes] struct {
+	nodeSize       TIX
+	maxNumChildren TIX
+	vectorLength   TIX
+}
+
+// Distance creates a new euclidean (L2) distance implementation.
+func Distance[TV interfaces.VectorType, TIX interfaces.IndexTypes](
+	vectorLength TIX,
+) *euclideanDistanceImpl[TV, TIX] {
+
+	n := EuclideanNodeImpl[TV, TIX]{}
+
+	ed := &euclideanDistanceImpl[TV, TIX]{
+		vectorLength: vectorLength,
+		nodeSize: TIX(
+			unsafe.Offsetof(n.v) +
+				(uintptr(vectorLength) * unsafe.Sizeof(TV(0))),
+		),
+	}
+
+	// _K = (S) (((size_t) (_s - offsetof(Node, children))) / sizeof(S));
+	size := uintptr(ed.nodeSize) - unsafe.Offsetof(n.children)
+	ed.maxNumChildren = TIX(size / unsafe.Sizeof(n.children[0]))
+
+	return ed
+}
+
+func (e *euclideanDistanceImpl[TV, TIX]) VectorLength() TIX {
+	return e.vectorLength
+}
+
+func (e *euclideanDistanceImpl[TV, TIX]) MaxNumChildren() TIX {
+	return e.maxNumChildren
+}
+
+func (e *euclideanDistanceImpl[TV, TIX]) NodeSize() TIX {
+	return e.nodeSize
+}
+
+func (e *euclideanDistanceImpl[TV, TIX]) MapNodeToMemory(
+	mem unsafe.Pointer,
+	itemIndex TIX,
+) interfaces.Node[TV, TIX] {
+	pos := unsafe.Add(mem, itemIndex*e.nodeSize)
+
+	return (*EuclideanNodeImpl[TV, TIX])(pos)
+}
+
+func (e *euclideanDistanceImpl[TV, TIX]) PreProcess(nodes unsafe.Pointer, node_count TIX) {
+	// DO NOTHING
+}
+
+func (e *euclideanDistanceImpl[TV, TIX]) Normalize(node interfaces.Node[TV, TIX]) {
+	raw := node.GetRawVector()
+	norm := TV(vector.GetNormUnsafe(raw, e.vectorLength))
+
+	if norm > 0 {
+		ptr := unsafe.Pointer(raw)
+		size := TIX(unsafe.Sizeof(TV(0)))
+
+		for i := TIX(0); i < e.vectorLength; i++ {
+			f := (*TV)(unsafe.Pointer(unsafe.Add(ptr, i*size)))
+			*f /= norm
+		}
+	}
+}
+
+func (e *euclideanDistanceImpl[TV, TIX]) Distance(x interfaces.Node[TV, TIX], y interfaces.Node[TV, TIX]) TV {
+	return vector.EuclideanDistanceUnsafe(x.GetRawVector(), y.GetRawVector(), e.vectorLength)
+}
+
+// Margin is the signed distance from _y_ to the split plane held by _n_.
+func (e *euclideanDistanceImpl[TV, TIX]) Margin(n interfaces.Node[TV, TIX], y []TV) TV {
+	if len(y) == 0 {
+		panic("y is empty")
+	}
+
+	return n.(*EuclideanNodeImpl[TV, TIX]).a + vector.DotUnsafe(
+		n.GetRawVector(),
+		(*TV)(unsafe.Pointer(unsafe.SliceData(y))),
+		e.vectorLength,
+	)
+}
+
+func (e *euclideanDistanceImpl[TV, TIX]) Side(
+	n interfaces.Node[TV, TIX],
+	y []TV,
+	random interfaces.Random[TIX],
+) interfaces.Side {
+
+	dot := e.Margin(n, y)
+
+	if dot != 0 {
+		if dot > 0 {
+			return interfaces.SideRight
+		} else {
+			return interfaces.SideLeft
+		}
+	}
+
+	return random.NextSide()
+}
+
+func (e *euclideanDistanceImpl[TV, TIX]) CreateSplit(
+	nodes []interfaces.Node[TV, TIX],
+	nodeSize TIX,
+	random interfaces.Random[TIX],
+	n interfaces.Node[TV, TIX],
+) {
+	// Allocate memory for two nodes, and use them as temporary nodes
+	p_mem := make([]byte, nodeSize)
+	q_mem := make([]byte, nodeSize)
+
+	p := (*EuclideanNodeImpl[TV, TIX])(unsafe.Pointer(unsafe.SliceData(p_mem)))
+	q := (*EuclideanNodeImpl[TV, TIX])(unsafe.Pointer(unsafe.SliceData(q_mem)))
+
+	distance.TwoMeans[TV, TIX](nodes, e.vectorLength, random, false, p, q, e)
+
+	nv := n.GetVector(e.vectorLength)
+	qv := q.GetVector(e.vectorLength)
+	pv := p.GetVector(e.vectorLength)
+
+	for z := TIX(0); z < e.vectorLength; z++ {
+		nv[z] = pv[z] - qv[z]
+	}
+
+	e.Normalize(n)
+
+	// The plane goes through the midpoint of the two centroids
+	var a TV
+
+	for z := TIX(0); z < e.vectorLength; z++ {
+		a += -nv[z] * (pv[z] + qv[z]) / 2
+	}
+
+	n.(*EuclideanNodeImpl[TV, TIX]).a = a
+}
+
+func (e *euclideanDistanceImpl[TV, _]) NormalizedDistance(distance TV) TV {
+	return TV(math.Sqrt(math.Max(float64(distance), 0)))
+}
+
+func (e *euclideanDistanceImpl[TV, TIX]) PQDistance(distance, margin TV, side interfaces.Side) TV {
+	if side == interfaces.SideLeft {
+		margin = -margin
+	}
+	return TV(math.Min(float64(distance), float64(margin)))
+}
+
+// MarginBound implements `interfaces.MarginBounder`. The split normal is normalized,
+// hence the margin is the distance to the split plane.
+func (e *euclideanDistanceImpl[TV, TIX]) MarginBound(margin TV) TV {
+	if margin < 0 {
+		return -margin
+	}
+
+	return margin
+}
+
+func (e *euclideanDistanceImpl[TV, TIX]) PQInitialValue() TV {
+	return TV(math.Inf(1))
+}
+
+// InitNode does nothing since the euclidean distance do not need any pre-calculated values.
+func (e *euclideanDistanceImpl[TV, TIX]) InitNode(node interfaces.Node[TV, TIX]) {
+	// DO NOTHING
+}
+
+func (e *euclideanDistanceImpl[TV, TIX]) Name() string {
+	return "euclidean"
+}
@@ -0,0 +1,96 @@
+package euclidean
+
+import (
+	"unsafe"
+
+	"github.com/mariotoffia/goannoy/interfaces"
+)
+
+// EuclideanNodeImpl is the node implementation for the euclidean distance.
+//
+// Note from the author:
+//
+// We store a binary tree where each node has two things
+// - A vector associated with it
+// - Two children
+// All nodes occupy the same amount of memory
+type EuclideanNodeImpl[TV interfaces.VectorType, TIX interfaces.IndexTypes] struct {
+	// n_descendants is the number of descendants of this node.
+	//
+	// * All nodes with n_descendants == 1 are leaf nodes.
+	// * For nodes with n_descendants == 1 the vector is a data point.
+	// * For nodes with n_descendants > K the vector is the normal of the split plane.
+	n_descendants TIX
+	// a is the extra constant term needed to determine the offset of the split plane.
+	a TV
+	// children will contain indexes to other nodes when n_descendants > 1.
+	//
+	// A memory optimization is when n_descendants >= 2 (and less than K, where K is the
+	// calculated maximum number of descendants that can fit instead of the vector).
+	// In that case no vector is stored and the memory is used for children only instead.
+	children [2]TIX
+	v        [0]TV
+}
+
+func (n *EuclideanNodeImpl[TV, TIX]) GetRawVector() *TV {
+	return (*TV)(unsafe.Pointer(&n.v))
+}
+
+func (n *EuclideanNodeImpl[TV, TIX]) GetVector(vectorLength TIX) []TV {
+	return unsafe.Slice((*TV)(unsafe.Pointer(&n.v)), vectorLength)
+}
+
+func (n *EuclideanNodeImpl[TV, TIX]) SetVector(v []TV) {
+	dst := unsafe.Pointer(&n.v)
+	src := unsafe.Pointer(unsafe.SliceData(v))
+	size := uintptr(len(v)) * unsafe.Sizeof(TV(0))
+
+	copy((*[1 << 30]byte)(dst)[:size], (*[1 << 30]byte)(src)[:size])
+}
+
+func (n *EuclideanNodeImpl[TV, TIX]) GetRawChildren() *TIX {
+	return (*TIX)(unsafe.Pointer(&n.children))
+}
+
+func (n *EuclideanNodeImpl[TV, TIX]) GetChildren() []TIX {
+	if n.n_descendants == 0 {
+		return nil
+	}
+
+	return unsafe.Slice((*TIX)(unsafe.Pointer(&n.children)), n.n_descendants)
+}
+
+func (n *EuclideanNodeImpl[TV, TIX]) SetChildren(children []TIX) {
+	dst := unsafe.Pointer(&n.children)
+	src := unsafe.Pointer(unsafe.SliceData(children))
+	size := uintptr(len(children)) * unsafe.Sizeof(n.children[0])
+
+	copy((*[1 << 30]byte)(dst)[:size], (*[1 << 30]byte)(src)[:size])
+}
+
+func (n *EuclideanNodeImpl[TV, TIX]) GetNumberOfDescendants() TIX {
+	return n.n_descendants
+}
+
+func (n *EuclideanNodeImpl[TV, TIX]) SetNumberOfDescendants(nDescendants TIX) {
+	n.n_descendants = nDescendants
+}
+
+// GetNorm always returns zero since the euclidean node do not store any norm.
+func (n *EuclideanNodeImpl[TV, TIX]) GetNorm() TV {
+	return 0
+}
+
+// SetNorm is a no-op since the euclidean node do not store any norm.
+func (n *EuclideanNodeImpl[TV, TIX]) SetNorm(norm TV) {
+}
+
+// GetOffset returns the constant term of the split plane (`a` in the original code).
+func (n *EuclideanNodeImpl[TV, TIX]) GetOffset() TV {
+	return n.a
+}
+
+// SetOffset sets the constant term of the split plane (`a` in the original code).
+func (n *EuclideanNodeImpl[TV, TIX]) SetOffset(a TV) {
+	n.a = a
+}
@@ -0,0 +1,180 @@
+package hamming
+
+import (
+	"math"
+	"math/bits"
+	"unsafe"
+
+	"github.com/mariotoffia/goannoy/interfaces"
+)
+
+// maxIterations is the number of random bit positions to try before falling back
+// to a brute-force search for a splitting bit.
+const maxIterations = 20
+
+// bitsPerElement is the number of bits packed into each vector element.
+const bitsPerElement = 64
+
+type hammingDistanceImpl[TIX interfaces.IndexTypes] struct {
+	nodeSize       TIX
+	maxNumChildren TIX
+	vectorLength   TIX
+}
+
+// Distance creates a new hamming distance implementation. The _vectorLength_ is the
+// number of `uint64` elements (i.e. 64 bits each) the packed bit vector consists of.
+func Distance[TIX interfaces.IndexTypes](
+	vectorLength TIX,
+) *hammingDistanceImpl[TIX] {
+
+	n := HammingNodeImpl[TIX]{}
+
+	hd := &hammingDistanceImpl[TIX]{
+		vectorLength: vectorLength,
+		nodeSize: TIX(
+			unsafe.Offsetof(n.v) +
+				(uintptr(vectorLength) * unsafe.Sizeof(uint64(0))),
+		),
+	}
+
+	// _K = (S) (((size_t) (_s - offsetof(Node, children))) / sizeof(S));
+	size := uintptr(hd.nodeSize) - unsafe.Offsetof(n.children)
+	hd.maxNumChildren = TIX(size / unsafe.Sizeof(n.children[0]))
+
+	return hd
+}
+
+func (h *hammingDistanceImpl[TIX]) VectorLength() TIX {
+	return h.vectorLength
+}
+
+func (h *hammingDistanceImpl[TIX]) MaxNumChildren() TIX {
+	return h.maxNumChildren
+}
+
+func (h *hammingDistanceImpl[TIX]) NodeSize() TIX {
+	return h.nodeSize
+}
+
+func (h *hammingDistanceImpl[TIX]) MapNodeToMemory(
+	mem unsafe.Pointer,
+	itemIndex TIX,
+) interfaces.Node[uint64, TIX] {
+	pos := unsafe.Add(mem, itemIndex*h.nodeSize)
+
+	return (*HammingNodeImpl[TIX])(pos)
+}
+
+func (h *hammingDistanceImpl[TIX]) PreProcess(nodes unsafe.Pointer, node_count TIX) {
+	// DO NOTHING
+}
+
+// Normalize is a no-op since a bit vector can't be normalized.
+func (h *hammingDistanceImpl[TIX]) Normalize(node interfaces.Node[uint64, TIX]) {
+	// DO NOTHING
+}
+
+// Distance is the number of differing bits between _x_ and _y_.
+func (h *hammingDistanceImpl[TIX]) Distance(x interfaces.Node[uint64, TIX], y interfaces.Node[uint64, TIX]) uint64 {
+	xv := x.GetVector(h.vectorLength)
+	yv := y.GetVector(h.vectorLength)
+
+	var dist uint64
+
+	for i := range xv {
+		dist += uint64(bits.OnesCount64(xv[i] ^ yv[i]))
+	}
+
+	return dist
+}
+
+// Margin returns 1 if the split bit held in the first vector element of _n_
+// is set in _y_, otherwise 0.
+func (h *hammingDistanceImpl[TIX]) Margin(n interfaces.Node[uint64, TIX], y []uint64) uint64 {
+	if len(y) == 0 {
+		panic("y is empty")
+	}
+
+	bit := *n.GetRawVector()
+	chunk := bit / bitsPerElement
+
+	return (y[chunk] >> (bitsPerElement - 1 - (bit % bitsPerElement))) & 1
+}
+
+func (h *hammingDistanceImpl[TIX]) Side(
+	n interfaces.Node[uint64, TIX],
+	y []uint64,
+	random interfaces.Random[TIX],
+) interfaces.Side {
+	if h.Margin(n, y) == 1 {
+		return interfaces.SideRight
+	}
+
+	return interfaces.SideLeft
+}
+
+// CreateSplit picks a random bit position that divides the _nodes_. If none is found
+// within `maxIterations` attempts, all bit positions are tried in order.
+func (h *hammingDistanceImpl[TIX]) CreateSplit(
+	nodes []interfaces.Node[uint64, TIX],
+	nodeSize TIX,
+	random interfaces.Random[TIX],
+	n interfaces.Node[uint64, TIX],
+) {
+	dim := h.vectorLength * bitsPerElement
+	split := n.GetRawVector()
+
+	divides := func() bool {
+		var cur_size int
+
+		for _, node := range nodes {
+			if h.Margin(n, node.GetVector(h.vectorLength)) == 1 {
+				cur_size++
+			}
+		}
+
+		return cur_size > 0 && cur_size < len(nodes)
+	}
+
+	for i := 0; i < maxIterations; i++ {
+		*split = uint64(random.NextIndex(dim))
+
+		if divides() {
+			return
+		}
+	}
+
+	// brute-force search for splitting coordinate
+	for j := TIX(0); j < dim; j++ {
+		*split = uint64(j)
+
+		if divides() {
+			return
+		}
+	}
+}
+
+func (h *hammingDistanceImpl[_]) NormalizedDistance(distance uint64) uint64 {
+	return distance
+}
+
+func (h *hammingDistanceImpl[TIX]) PQDistance(distance, margin uint64, side interfaces.Side) uint64 {
+	if margin != uint64(side) {
+		return distance - 1
+	}
+
+	return distance
+}
+
+func (h *hammingDistanceImpl[TIX]) PQInitialValue() uint64 {
+	return math.MaxUint64
+}
+
+// InitNode does nothing since the hamming distance do not need any pre-calculated values.
+func (h *hammingDistanceImpl[TIX]) InitNode(node interfaces.Node[uint64, TIX]) {
+	// DO NOTHING
+}
+
+func (h *hammingDistanceImpl[TIX]) Name() string {
+	return "hamming"
+}
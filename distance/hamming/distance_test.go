@@ -0,0 +1,72 @@
+package hamming_test
+
+import (
+	"testing"
+
+	"github.com/mariotoffia/goannoy/builder"
+	"github.com/mariotoffia/goannoy/interfaces"
+	"github.com/stretchr/testify/assert"
+)
+
+func createIndex(vectorLength int) interfaces.AnnoyIndex[uint64, uint32] {
+	return builder.Index[uint64, uint32]().
+		HammingDistance(vectorLength).
+		SingleWorkerPolicy().
+		Build()
+}
+
+func TestGetNnsByVectorReturnsCorrectIndexes(t *testing.T) {
+	idx := createIndex(1)
+	defer idx.Close()
+
+	idx.AddItem(0, []uint64{0x0})
+	idx.AddItem(1, []uint64{0xFF})
+	idx.AddItem(2, []uint64{0xFFFF})
+	idx.Build(10, -1)
+
+	ctx := idx.CreateContext()
+
+	result, distances := idx.GetNnsByVector([]uint64{0x1}, 3, -1, ctx)
+	assert.Equal(t, []uint32{0, 1, 2}, result)
+	assert.Equal(t, []uint64{1, 7, 15}, distances)
+
+	result, _ = idx.GetNnsByVector([]uint64{0xFFFE}, 3, -1, ctx)
+	assert.Equal(t, []uint32{2, 1, 0}, result)
+}
+
+func TestGetDistance(t *testing.T) {
+	idx := createIndex(2)
+	defer idx.Close()
+
+	idx.AddItem(0, []uint64{0x0, 0x0})
+	idx.AddItem(1, []uint64{0xF, 0xFFFFFFFFFFFFFFFF})
+	idx.Build(10, -1)
+
+	assert.Equal(t, uint64(68), idx.GetDistance(0, 1))
+	assert.Equal(t, uint64(0), idx.GetDistance(1, 1))
+}
+
+func TestManyItemsSplitsOnBits(t *testing.T) {
+	idx := createIndex(1)
+	defer idx.Close()
+
+	for i := uint32(0); i < 256; i++ {
+		idx.AddItem(i, []uint64{uint64(i)})
+	}
+
+	idx.Build(10, -1)
+
+	ctx := idx.CreateContext()
+
+	result, distances := idx.GetNnsByVector([]uint64{0x0}, 1, 10000, ctx)
+	assert.Equal(t, []uint32{0}, result)
+	assert.Equal(t, []uint64{0}, distances)
+
+	assert.Equal(t, []uint64{0xAA}, idx.GetItem(0xAA))
+}
+
+func TestHammingRequiresUint64(t *testing.T) {
+	assert.Panics(t, func() {
+		builder.Index[float32, uint32]().HammingDistance(1)
+	})
+}
@@ -0,0 +1,81 @@
+package hamming
+
+import (
+	"unsafe"
+
+	"github.com/mariotoffia/goannoy/interfaces"
+)
+
+// HammingNodeImpl is the node implementation for the hamming distance.
+//
+// The vector is a packed bit vector where each element holds 64 bits. A split
+// node do not store a hyperplane, instead the first element of the vector holds
+// the bit position that the split is made on.
+type HammingNodeImpl[TIX interfaces.IndexTypes] struct {
+	// n_descendants is the number of descendants of this node.
+	//
+	// * All nodes with n_descendants == 1 are leaf nodes.
+	// * For nodes with n_descendants == 1 the vector is a data point.
+	// * For nodes with n_descendants > K the first vector element is the split bit.
+	n_descendants TIX
+	// children will contain indexes to other nodes when n_descendants > 1.
+	//
+	// A memory optimization is when n_descendants >= 2 (and less than K, where K is the
+	// calculated maximum number of descendants that can fit instead of the vector).
+	// In that case no vector is stored and the memory is used for children only instead.
+	children [2]TIX
+	v        [0]uint64
+}
+
+func (n *HammingNodeImpl[TIX]) GetRawVector() *uint64 {
+	return (*uint64)(unsafe.Pointer(&n.v))
+}
+
+func (n *HammingNodeImpl[TIX]) GetVector(vectorLength TIX) []uint64 {
+	return unsafe.Slice((*uint64)(unsafe.Pointer(&n.v)), vectorLength)
+}
+
+func (n *HammingNodeImpl[TIX]) SetVector(v []uint64) {
+	dst := unsafe.Pointer(&n.v)
+	src := unsafe.Pointer(unsafe.SliceData(v))
+	size := uintptr(len(v)) * unsafe.Sizeof(uint64(0))
+
+	copy((*[1 << 30]byte)(dst)[:size], (*[1 << 30]byte)(src)[:size])
+}
+
+func (n *HammingNodeImpl[TIX]) GetRawChildren() *TIX {
+	return (*TIX)(unsafe.Pointer(&n.children))
+}
+
+func (n *HammingNodeImpl[TIX]) GetChildren() []TIX {
+	if n.n_descendants == 0 {
+		return nil
+	}
+
+	return unsafe.Slice((*TIX)(unsafe.Pointer(&n.children)), n.n_descendants)
+}
+
+func (n *HammingNodeImpl[TIX]) SetChildren(children []TIX) {
+	dst := unsafe.Pointer(&n.children)
+	src := unsafe.Pointer(unsafe.SliceData(children))
+	size := uintptr(len(children)) * unsafe.Sizeof(n.children[0])
+
+	copy((*[1 << 30]byte)(dst)[:size], (*[1 << 30]byte)(src)[:size])
+}
+
+func (n *HammingNodeImpl[TIX]) GetNumberOfDescendants() TIX {
+	return n.n_descendants
+}
+
+func (n *HammingNodeImpl[TIX]) SetNumberOfDescendants(nDescendants TIX) {
+	n.n_descendants = nDescendants
+}
+
+// GetNorm always returns zero since the hamming node do not store any norm.
+func (n *HammingNodeImpl[TIX]) GetNorm() uint64 {
+	return 0
+}
+
+// SetNorm is a no-op since the hamming node do not store any norm.
+func (n *HammingNodeImpl[TIX]) SetNorm(norm uint64) {
+}
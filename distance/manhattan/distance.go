@@ -0,0 +1,184 @@
+package manhattan
+
+import (
+	"math"
+	"unsafe"
+
+	"github.com/mariotoffia/goannoy/distance"
+	"github.com/mariotoffia/goannoy/interfaces"
+	"github.com/mariotoffia/goannoy/vector"
+)
+
+type manhattanDistanceImpl[TV interfaces.VectorType, TIX interfaces.IndexTypes] struct {
+	nodeSize       TIX
+	maxNumChildren TIX
+	vectorLength   TIX
+}
+
+// Distance creates a new manhattan (L1) distance implementation.
+func Distance[TV interfaces.VectorType, TIX interfaces.IndexTypes](
+	vectorLength TIX,
+) *manhattanDistanceImpl[TV, TIX] {
+
+	n := ManhattanNodeImpl[TV, TIX]{}
+
+	md := &manhattanDistanceImpl[TV, TIX]{
+		vectorLength: vectorLength,
+		nodeSize: TIX(
+			unsafe.Offsetof(n.v) +
+				(uintptr(vectorLength) * unsafe.Sizeof(TV(0))),
+		),
+	}
+
+	// _K = (S) (((size_t) (_s - offsetof(Node, children))) / sizeof(S));
+	size := uintptr(md.nodeSize) - unsafe.Offsetof(n.children)
+	md.maxNumChildren = TIX(size / unsafe.Sizeof(n.children[0]))
+
+	return md
+}
+
+func (m *manhattanDistanceImpl[TV, TIX]) VectorLength() TIX {
+	return m.vectorLength
+}
+
+func (m *manhattanDistanceImpl[TV, TIX]) MaxNumChildren() TIX {
+	return m.maxNumChildren
+}
+
+func (m *manhattanDistanceImpl[TV, TIX]) NodeSize() TIX {
+	return m.nodeSize
+}
+
+func (m *manhattanDistanceImpl[TV, TIX]) MapNodeToMemory(
+	mem unsafe.Pointer,
+	itemIndex TIX,
+) interfaces.Node[TV, TIX] {
+	pos := unsafe.Add(mem, itemIndex*m.nodeSize)
+
+	return (*ManhattanNodeImpl[TV, TIX])(pos)
+}
+
+func (m *manhattanDistanceImpl[TV, TIX]) PreProcess(nodes unsafe.Pointer, node_count TIX) {
+	// DO NOTHING
+}
+
+func (m *manhattanDistanceImpl[TV, TIX]) Normalize(node interfaces.Node[TV, TIX]) {
+	raw := node.GetRawVector()
+	norm := TV(vector.GetNormUnsafe(raw, m.vectorLength))
+
+	if norm > 0 {
+		ptr := unsafe.Pointer(raw)
+		size := TIX(unsafe.Sizeof(TV(0)))
+
+		for i := TIX(0); i < m.vectorLength; i++ {
+			f := (*TV)(unsafe.Pointer(unsafe.Add(ptr, i*size)))
+			*f /= norm
+		}
+	}
+}
+
+func (m *manhattanDistanceImpl[TV, TIX]) Distance(x interfaces.Node[TV, TIX], y interfaces.Node[TV, TIX]) TV {
+	return vector.ManhattanDistance(x.GetVector(m.vectorLength), y.GetVector(m.vectorLength), m.vectorLength)
+}
+
+// Margin is the signed distance from _y_ to the split plane held by _n_.
+func (m *manhattanDistanceImpl[TV, TIX]) Margin(n interfaces.Node[TV, TIX], y []TV) TV {
+	if len(y) == 0 {
+		panic("y is empty")
+	}
+
+	return n.(*ManhattanNodeImpl[TV, TIX]).a + vector.DotUnsafe(
+		n.GetRawVector(),
+		(*TV)(unsafe.Pointer(unsafe.SliceData(y))),
+		m.vectorLength,
+	)
+}
+
+func (m *manhattanDistanceImpl[TV, TIX]) Side(
+	n interfaces.Node[TV, TIX],
+	y []TV,
+	random interfaces.Random[TIX],
+) interfaces.Side {
+
+	dot := m.Margin(n, y)
+
+	if dot != 0 {
+		if dot > 0 {
+			return interfaces.SideRight
+		} else {
+			return interfaces.SideLeft
+		}
+	}
+
+	return random.NextSide()
+}
+
+func (m *manhattanDistanceImpl[TV, TIX]) CreateSplit(
+	nodes []interfaces.Node[TV, TIX],
+	nodeSize TIX,
+	random interfaces.Random[TIX],
+	n interfaces.Node[TV, TIX],
+) {
+	// Allocate memory for two nodes, and use them as temporary nodes
+	p_mem := make([]byte, nodeSize)
+	q_mem := make([]byte, nodeSize)
+
+	p := (*ManhattanNodeImpl[TV, TIX])(unsafe.Pointer(unsafe.SliceData(p_mem)))
+	q := (*ManhattanNodeImpl[TV, TIX])(unsafe.Pointer(unsafe.SliceData(q_mem)))
+
+	distance.TwoMeans[TV, TIX](nodes, m.vectorLength, random, false, p, q, m)
+
+	nv := n.GetVector(m.vectorLength)
+	qv := q.GetVector(m.vectorLength)
+	pv := p.GetVector(m.vectorLength)
+
+	for z := TIX(0); z < m.vectorLength; z++ {
+		nv[z] = pv[z] - qv[z]
+	}
+
+	m.Normalize(n)
+
+	// The plane goes through the midpoint of the two centroids
+	var a TV
+
+	for z := TIX(0); z < m.vectorLength; z++ {
+		a += -nv[z] * (pv[z] + qv[z]) / 2
+	}
+
+	n.(*ManhattanNodeImpl[TV, TIX]).a = a
+}
+
+func (m *manhattanDistanceImpl[TV, _]) NormalizedDistance(distance TV) TV {
+	return TV(math.Max(float64(distance), 0))
+}
+
+func (m *manhattanDistanceImpl[TV, TIX]) PQDistance(distance, margin TV, side interfaces.Side) TV {
+	if side == interfaces.SideLeft {
+		margin = -margin
+	}
+	return TV(math.Min(float64(distance), float64(margin)))
+}
+
+// MarginBound implements `interfaces.MarginBounder`. The split normal is normalized,
+// hence the margin is the euclidean distance to the split plane, which never exceeds
+// the manhattan distance.
+func (m *manhattanDistanceImpl[TV, TIX]) MarginBound(margin TV) TV {
+	if margin < 0 {
+		return -margin
+	}
+
+	return margin
+}
+
+func (m *manhattanDistanceImpl[TV, TIX]) PQInitialValue() TV {
+	return TV(math.Inf(1))
+}
+
+// InitNode does nothing since the manhattan distance do not need any pre-calculated values.
+func (m *manhattanDistanceImpl[TV, TIX]) InitNode(node interfaces.Node[TV, TIX]) {
+	// DO NOTHING
+}
+
+func (m *manhattanDistanceImpl[TV, TIX]) Name() string {
+	return "manhattan"
+}
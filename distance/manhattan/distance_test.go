@@ -0,0 +1,68 @@
+package manhattan_test
+
+import (
+	"testing"
+
+	"github.com/mariotoffia/goannoy/builder"
+	"github.com/mariotoffia/goannoy/interfaces"
+	"github.com/stretchr/testify/assert"
+)
+
+func createIndex(vectorLength int) interfaces.AnnoyIndex[float32, uint32] {
+	return builder.Index[float32, uint32]().
+		ManhattanDistance(vectorLength).
+		SingleWorkerPolicy().
+		Build()
+}
+
+func TestGetNnsByVectorReturnsCorrectIndexes(t *testing.T) {
+	idx := createIndex(2)
+	defer idx.Close()
+
+	idx.AddItem(0, []float32{2, 2})
+	idx.AddItem(1, []float32{3, 2})
+	idx.AddItem(2, []float32{3, 3})
+	idx.Build(10, -1)
+
+	ctx := idx.CreateContext()
+
+	result, _ := idx.GetNnsByVector([]float32{4, 4}, 3, -1, ctx)
+	assert.Equal(t, []uint32{2, 1, 0}, result)
+
+	result, _ = idx.GetNnsByVector([]float32{1, 1}, 3, -1, ctx)
+	assert.Equal(t, []uint32{0, 1, 2}, result)
+
+	result, _ = idx.GetNnsByVector([]float32{5, 3}, 3, -1, ctx)
+	assert.Equal(t, []uint32{2, 1, 0}, result)
+}
+
+func TestGetDistance(t *testing.T) {
+	idx := createIndex(2)
+	defer idx.Close()
+
+	idx.AddItem(0, []float32{0, 0})
+	idx.AddItem(1, []float32{3, 4})
+	idx.AddItem(2, []float32{-1, 1})
+	idx.Build(10, -1)
+
+	assert.Equal(t, float32(7), idx.GetDistance(0, 1))
+	assert.Equal(t, float32(2), idx.GetDistance(0, 2))
+	assert.Equal(t, float32(7), idx.GetDistance(1, 2))
+}
+
+func TestDistancesAreNotSquared(t *testing.T) {
+	idx := createIndex(2)
+	defer idx.Close()
+
+	for i := 0; i < 100; i++ {
+		idx.AddItem(uint32(i), []float32{float32(i), float32(i)})
+	}
+
+	idx.Build(10, -1)
+
+	ctx := idx.CreateContext()
+
+	result, distances := idx.GetNnsByVector([]float32{0, 0}, 3, 1000, ctx)
+	assert.Equal(t, []uint32{0, 1, 2}, result)
+	assert.Equal(t, []float32{0, 2, 4}, distances)
+}
@@ -0,0 +1,96 @@
+package manhattan
+
+import (
+	"unsafe"
+
+	"github.com/mariotoffia/goannoy/interfaces"
+)
+
+// ManhattanNodeImpl is the node implementation for the manhattan distance.
+//
+// Note from the author:
+//
+// We store a binary tree where each node has two things
+// - A vector associated with it
+// - Two children
+// All nodes occupy the same amount of memory
+type ManhattanNodeImpl[TV interfaces.VectorType, TIX interfaces.IndexTypes] struct {
+	// n_descendants is the number of descendants of this node.
+	//
+	// * All nodes with n_descendants == 1 are leaf nodes.
+	// * For nodes with n_descendants == 1 the vector is a data point.
+	// * For nodes with n_descendants > K the vector is the normal of the split plane.
+	n_descendants TIX
+	// a is the extra constant term needed to determine the offset of the split plane.
+	a TV
+	// children will contain indexes to other nodes when n_descendants > 1.
+	//
+	// A memory optimization is when n_descendants >= 2 (and less than K, where K is the
+	// calculated maximum number of descendants that can fit instead of the vector).
+	// In that case no vector is stored and the memory is used for children only instead.
+	children [2]TIX
+	v        [0]TV
+}
+
+func (n *ManhattanNodeImpl[TV, TIX]) GetRawVector() *TV {
+	return (*TV)(unsafe.Pointer(&n.v))
+}
+
+func (n *ManhattanNodeImpl[TV, TIX]) GetVector(vectorLength TIX) []TV {
+	return unsafe.Slice((*TV)(unsafe.Pointer(&n.v)), vectorLength)
+}
+
+func (n *ManhattanNodeImpl[TV, TIX]) SetVector(v []TV) {
+	dst := unsafe.Pointer(&n.v)
+	src := unsafe.Pointer(unsafe.SliceData(v))
+	size := uintptr(len(v)) * unsafe.Sizeof(TV(0))
+
+	copy((*[1 << 30]byte)(dst)[:size], (*[1 << 30]byte)(src)[:size])
+}
+
+func (n *ManhattanNodeImpl[TV, TIX]) GetRawChildren() *TIX {
+	return (*TIX)(unsafe.Pointer(&n.children))
+}
+
+func (n *ManhattanNodeImpl[TV, TIX]) GetChildren() []TIX {
+	if n.n_descendants == 0 {
+		return nil
+	}
+
+	return unsafe.Slice((*TIX)(unsafe.Pointer(&n.children)), n.n_descendants)
+}
+
+func (n *ManhattanNodeImpl[TV, TIX]) SetChildren(children []TIX) {
+	dst := unsafe.Pointer(&n.children)
+	src := unsafe.Pointer(unsafe.SliceData(children))
+	size := uintptr(len(children)) * unsafe.Sizeof(n.children[0])
+
+	copy((*[1 << 30]byte)(dst)[:size], (*[1 << 30]byte)(src)[:size])
+}
+
+func (n *ManhattanNodeImpl[TV, TIX]) GetNumberOfDescendants() TIX {
+	return n.n_descendants
+}
+
+func (n *ManhattanNodeImpl[TV, TIX]) SetNumberOfDescendants(nDescendants TIX) {
+	n.n_descendants = nDescendants
+}
+
+// GetNorm always returns zero since the manhattan node do not store any norm.
+func (n *ManhattanNodeImpl[TV, TIX]) GetNorm() TV {
+	return 0
+}
+
+// SetNorm is a no-op since the manhattan node do not store any norm.
+func (n *ManhattanNodeImpl[TV, TIX]) SetNorm(norm TV) {
+}
+
+// GetOffset returns the constant term of the split plane (`a` in the original code).
+func (n *ManhattanNodeImpl[TV, TIX]) GetOffset() TV {
+	return n.a
+}
+
+// SetOffset sets the constant term of the split plane (`a` in the original code).
+func (n *ManhattanNodeImpl[TV, TIX]) SetOffset(a TV) {
+	n.a = a
+}
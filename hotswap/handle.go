@@ -0,0 +1,212 @@
+// Package hotswap holds an `interfaces.AnnoyIndex` that can be replaced, e.g. by a newly
+// built index file, while it is being searched.
+package hotswap
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/mariotoffia/goannoy/interfaces"
+)
+
+// ErrClosed is returned when reloading a closed handle.
+var ErrClosed = errors.New("handle is closed")
+
+// generation is a single index served by the handle. It is closed when the handle, and
+// all leases, have released it.
+type generation[TV interfaces.VectorType, TIX interfaces.IndexTypes] struct {
+	index interfaces.AnnoyIndex[TV, TIX]
+	// refs is the number of leases plus one for the handle while it is the current generation.
+	refs atomic.Int64
+	// contexts is a pool of contexts created by the index.
+	contexts sync.Pool
+}
+
+// acquire increments the reference count unless the generation already has been released.
+func (g *generation[TV, TIX]) acquire() bool {
+	for {
+		refs := g.refs.Load()
+
+		if refs == 0 {
+			return false
+		}
+
+		if g.refs.CompareAndSwap(refs, refs+1) {
+			return true
+		}
+	}
+}
+
+// release decrements the reference count and closes the index when it reaches zero.
+func (g *generation[TV, TIX]) release() error {
+	if g.refs.Add(-1) != 0 {
+		return nil
+	}
+
+	return g.index.Close()
+}
+
+// Lease is a reference to the index that was current when acquired. The index is not
+// closed before the lease is released, even if the handle has swapped in another index.
+type Lease[TV interfaces.VectorType, TIX interfaces.IndexTypes] struct {
+	gen *generation[TV, TIX]
+	ctx interfaces.AnnoyIndexContext[TV, TIX]
+	// released is set by the first `Release`, further calls do nothing.
+	released atomic.Bool
+}
+
+// Index returns the leased index. It *must not* be used after `Release`.
+func (l *Lease[TV, TIX]) Index() interfaces.AnnoyIndex[TV, TIX] {
+	return l.gen.index
+}
+
+// Context returns a context for the leased index. The context is returned to a pool when the
+// lease is released and hence, it *must not* be used after `Release`.
+func (l *Lease[TV, TIX]) Context() interfaces.AnnoyIndexContext[TV, TIX] {
+	if l.ctx == nil {
+		if ctx, ok := l.gen.contexts.Get().(interfaces.AnnoyIndexContext[TV, TIX]); ok {
+			l.ctx = ctx
+		} else {
+			l.ctx = l.gen.index.CreateContext()
+		}
+	}
+
+	return l.ctx
+}
+
+// Release releases the lease. If the index has been swapped out and this is the last
+// lease, the index is closed. Releasing an already released lease does nothing.
+func (l *Lease[TV, TIX]) Release() error {
+	if !l.released.CompareAndSwap(false, true) {
+		return nil
+	}
+
+	if l.ctx != nil {
+		l.gen.contexts.Put(l.ctx)
+		l.ctx = nil
+	}
+
+	return l.gen.release()
+}
+
+// Handle serves an index that may be swapped for another without blocking the searches. The
+// old index is closed, e.g. unmapped, once all in-flight searches using it have finished.
+type Handle[TV interfaces.VectorType, TIX interfaces.IndexTypes] struct {
+	current atomic.Pointer[generation[TV, TIX]]
+	// factory creates an empty index to load into.
+	factory func() interfaces.AnnoyIndex[TV, TIX]
+	// lock serializes swaps.
+	lock   sync.Mutex
+	closed bool
+}
+
+// New creates an empty handle. The _factory_ creates the empty index that `Reload` loads a
+// file into, e.g. using the `builder` with the same configuration as when the file was built.
+func New[TV interfaces.VectorType, TIX interfaces.IndexTypes](
+	factory func() interfaces.AnnoyIndex[TV, TIX],
+) *Handle[TV, TIX] {
+	return &Handle[TV, TIX]{factory: factory}
+}
+
+// Reload loads _fileName_ into a fresh index and swaps it in. If the load fails, the current
+// index is kept.
+func (h *Handle[TV, TIX]) Reload(fileName string) error {
+	index := h.factory()
+
+	if err := index.Load(fileName); err != nil {
+		index.Close()
+
+		return err
+	}
+
+	return h.Swap(index)
+}
+
+// Swap makes _index_ the current index and releases the previous one. The handle takes
+// ownership of the _index_ and closes it when it is swapped out and no longer leased.
+func (h *Handle[TV, TIX]) Swap(index interfaces.AnnoyIndex[TV, TIX]) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.closed {
+		index.Close()
+
+		return ErrClosed
+	}
+
+	gen := &generation[TV, TIX]{index: index}
+	gen.refs.Store(1)
+
+	if old := h.current.Swap(gen); old != nil {
+		return old.release()
+	}
+
+	return nil
+}
+
+// Acquire leases the current index. It returns `nil` when no index has been swapped in or
+// the handle is closed. The lease *must* be released when done with the index.
+func (h *Handle[TV, TIX]) Acquire() *Lease[TV, TIX] {
+	for {
+		gen := h.current.Load()
+
+		if gen == nil {
+			return nil
+		}
+
+		if gen.acquire() {
+			return &Lease[TV, TIX]{gen: gen}
+		}
+
+		// Swapped out and released in between, retry with the new current generation
+	}
+}
+
+// GetNnsByVector searches the current index using a pooled context. See
+// `interfaces.AnnoyIndex.GetNnsByVector`.
+func (h *Handle[TV, TIX]) GetNnsByVector(
+	vector []TV,
+	numReturn, numNodesToInspect int,
+) (result []TIX, distances []TV) {
+	lease := h.Acquire()
+
+	if lease == nil {
+		return nil, nil
+	}
+
+	defer lease.Release()
+
+	return lease.Index().GetNnsByVector(vector, numReturn, numNodesToInspect, lease.Context())
+}
+
+// GetNnsByItem searches the current index using a pooled context. See
+// `interfaces.AnnoyIndex.GetNnsByItem`.
+func (h *Handle[TV, TIX]) GetNnsByItem(
+	item TIX,
+	numReturn, numNodesToInspect int,
+) (result []TIX, distances []TV) {
+	lease := h.Acquire()
+
+	if lease == nil {
+		return nil, nil
+	}
+
+	defer lease.Release()
+
+	return lease.Index().GetNnsByItem(item, numReturn, numNodesToInspect, lease.Context())
+}
+
+// Close releases the current index. It is closed when the last lease is released.
+func (h *Handle[TV, TIX]) Close() error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.closed = true
+
+	if old := h.current.Swap(nil); old != nil {
+		return old.release()
+	}
+
+	return nil
+}
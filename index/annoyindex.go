@@ -1,8 +1,12 @@
 package index
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 	"math"
+	"sync"
+	"time"
 	"unsafe"
 
 	"github.com/mariotoffia/goannoy/interfaces"
@@ -38,7 +42,7 @@ type AnnoyIndexImpl[
 	// batchMaxNNS is the maximum of indexes that a query can possibly create.
 	// This is updated each time a index is loaded.
 	batchMaxNNS          int
-	logVerbose           bool
+	logger               *slog.Logger
 	maxDescendants       TIX
 	random               interfaces.Random[TIX]
 	indexLoaded          bool
@@ -49,6 +53,42 @@ type AnnoyIndexImpl[
 	indexMemoryAllocator interfaces.IndexAllocator
 	indexMemory          interfaces.AllocatedIndex
 	sorter               interfaces.Sorter[TV, TIX]
+	// fileHeader is set when `Save` shall write a `fileHeader` in front of the nodes.
+	fileHeader bool
+	// annoyCompatible is set when the saved files must be compatible with Spotify Annoy.
+	annoyCompatible bool
+	// buildProgress is the state of an ongoing build, it is only accessed while holding
+	// the roots lock.
+	buildProgress buildProgressState
+	// contexts is a pool of `BatchContext` used by `GetNnsByVectors`.
+	contexts sync.Pool
+	// incremental is set when items may be added after the index has been built.
+	incremental bool
+	// delta holds the items added after build when in incremental mode.
+	delta deltaSegment[TIX]
+	// deleted is the items marked as deleted, `nil` if none.
+	deleted *utils.Bitset[TIX]
+	// notDeleted is the search filter skipping deleted items, created once to not allocate
+	// on each search.
+	notDeleted func(item TIX) bool
+	// loadedFile is the file name of the loaded index.
+	loadedFile string
+	// numWorkers is the number of workers of the last build, used by `Compact`. It is -1,
+	// i.e. all CPU cores, if the index has not been built, e.g. loaded.
+	numWorkers int
+	// lock makes the index safe for concurrent use. Searches and other reads hold it for
+	// reading, whereas `Load`, `Close`, builds and modifications hold it for writing.
+	lock sync.RWMutex
+}
+
+// buildProgressState keeps track of the progress of an ongoing build.
+type buildProgressState struct {
+	progress      func(progress interfaces.BuildProgress)
+	numberOfTrees int
+	treesBuilt    int
+	start         time.Time
+	// cancel stops the build with the cause, e.g. when a worker fails to allocate memory.
+	cancel context.CancelCauseFunc
 }
 
 // New create a new index instance based on the _TV_ for the vector
@@ -67,10 +107,13 @@ type AnnoyIndexImpl[
 //
 // The _indexMemoryAllocator_ is the allocator to use for the index memory when loading it from
 // file. See `package memory` for more information. It is possible to output to stdout by setting
-// _logVerbose_ to `true`. This will output the progress of the index creation.
+// _logVerbose_ to `true`. This will output the progress of the index creation including node
+// dumps. Use the `WithLogger` option to log to a `slog.Logger` instead.
 //
 // Use `AddIndex` and when done, `Build` to build the index. `Save` the index, and thus is then
 // ready to be used for lookups.
+//
+// Optional behaviour, such as writing a file header, is configured using _opts_.
 func New[
 	TV interfaces.VectorType,
 	TIX interfaces.IndexTypes](
@@ -82,6 +125,7 @@ func New[
 	sorter interfaces.Sorter[TV, TIX],
 	logVerbose bool,
 	hintNumIndexes TIX,
+	opts ...Option[TV, TIX],
 ) interfaces.AnnoyIndex[TV, TIX] {
 	//
 	if sorter == nil {
@@ -97,24 +141,50 @@ func New[
 		nodeSize:             distance.NodeSize(),       // _s
 		maxDescendants:       distance.MaxNumChildren(), // _K
 		indexBuilt:           false,                     // _built
-		logVerbose:           logVerbose,                // _verbose
 		distance:             distance,
 		allocator:            allocator,
 		buildPolicy:          buildPolicy,
 		indexMemoryAllocator: indexMemoryAllocator,
 		sorter:               sorter,
+		numWorkers:           -1,
+	}
+
+	index.notDeleted = func(item TIX) bool {
+		return !index.deleted.Contains(item)
+	}
+
+	for _, opt := range opts {
+		opt(index)
+	}
+
+	if index.logger == nil {
+		index.logger = defaultLogger(logVerbose) // _verbose
 	}
 
-	// Pre-allocate memory for the index if hintNumIndexes is set > 0
+	// Pre-allocate memory for the index if hintNumIndexes is set > 0. It is only a hint, if
+	// it fails the error surfaces when adding items.
 	if hintNumIndexes > 0 {
-		allocator.Reallocate(int(float64(distance.NodeSize()*hintNumIndexes) * reallocation_factor))
+		_, _ = allocator.Reallocate(
+			int(float64(distance.NodeSize()*hintNumIndexes) * reallocation_factor),
+		)
 	}
 
 	return index
 }
 
 // Implements `io.Closer` interface
+//
+// It waits for ongoing searches to finish. Searches made after the index has been closed
+// return no items.
 func (idx *AnnoyIndexImpl[TV, TIX]) Close() error {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+
+	return idx.close()
+}
+
+// close is `Close` without locking.
+func (idx *AnnoyIndexImpl[TV, TIX]) close() error {
 	var err error
 
 	if idx.indexMemory != nil {
@@ -128,11 +198,16 @@ func (idx *AnnoyIndexImpl[TV, TIX]) Close() error {
 
 	idx._nodes = nil
 	idx.indexLoaded = false
+	idx.indexBuilt = false
 	idx._n_items = 0
 	idx._n_nodes = 0
 	idx._nodes_size = 0
 	idx.random = idx.random.CloneAndReset()
 	idx._roots = nil
+	idx.contexts = sync.Pool{}
+	idx.delta = deltaSegment[TIX]{}
+	idx.deleted = nil
+	idx.loadedFile = ""
 
 	return err
 }
@@ -142,26 +217,110 @@ func (idx *AnnoyIndexImpl[TV, TIX]) VectorLength() TIX {
 	return idx.vectorLength
 }
 
+func (idx *AnnoyIndexImpl[TV, TIX]) GetNumberOfItems() TIX {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+
+	return idx.numberOfItems()
+}
+
+// numberOfItems is `GetNumberOfItems` without locking.
+func (idx *AnnoyIndexImpl[TV, TIX]) numberOfItems() TIX {
+	n := idx._n_items
+
+	for _, item := range idx.delta.items {
+		if item >= n {
+			n = item + 1
+		}
+	}
+
+	return n
+}
+
+func (idx *AnnoyIndexImpl[TV, TIX]) GetNumberOfTrees() int {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+
+	return len(idx._roots)
+}
+
+// GetItem returns the vector of the _itemIndex_ or `nil` if the index has been closed.
 func (idx *AnnoyIndexImpl[TV, TIX]) GetItem(itemIndex TIX) []TV {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+
+	return idx.getItem(itemIndex)
+}
+
+// getItem is `GetItem` without locking.
+func (idx *AnnoyIndexImpl[TV, TIX]) getItem(itemIndex TIX) []TV {
+	if slot, ok := idx.delta.slots[itemIndex]; ok {
+		return idx.getDeltaNode(slot).GetVector(idx.vectorLength)
+	}
+
+	if idx._nodes == nil {
+		return nil
+	}
+
 	return idx.getNode(itemIndex).GetVector(idx.vectorLength)
 }
 
 func (idx *AnnoyIndexImpl[TV, TIX]) AddItem(itemIndex TIX, v []TV) {
+	if err := idx.AddItemE(itemIndex, v); err != nil {
+		panic(err.Error())
+	}
+}
+
+func (idx *AnnoyIndexImpl[TV, TIX]) AddItemE(itemIndex TIX, v []TV) error {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+
+	if idx.incremental && (idx.indexLoaded || idx.indexBuilt) {
+		if idx.vectorLength != TIX(len(v)) {
+			return fmt.Errorf("%w: %d != %d", ErrDimensionMismatch, idx.vectorLength, len(v))
+		}
+
+		idx.addDeltaItem(itemIndex, v)
+		idx.undelete(itemIndex)
+
+		return nil
+	}
+
+	if err := idx.addItem(itemIndex, v); err != nil {
+		return err
+	}
+
+	idx.undelete(itemIndex)
+
+	return nil
+}
+
+// addItem adds the item to the index memory, i.e. the items that trees are built from.
+func (idx *AnnoyIndexImpl[TV, TIX]) addItem(itemIndex TIX, v []TV) error {
 	if idx.indexLoaded {
-		panic("Can't add items to a loaded index")
+		return fmt.Errorf("%w: can't add items to a loaded index", ErrIndexLoaded)
+	}
+
+	if idx.indexBuilt {
+		return fmt.Errorf("%w: can't add items to a built index", ErrAlreadyBuilt)
 	}
 
 	if idx.vectorLength != TIX(len(v)) {
-		panic(fmt.Sprintf("Vector length mismatch: %d != %d", idx.vectorLength, len(v)))
+		return fmt.Errorf("%w: %d != %d", ErrDimensionMismatch, idx.vectorLength, len(v))
 	}
 
 	// Ensure that we have enough memory for the new node
-	idx.allocateSize(itemIndex+1, nil)
+	if err := idx.allocateSize(itemIndex+1, nil); err != nil {
+		return err
+	}
 
 	// Map the node onto the memory
 	node := idx.getNode(itemIndex)
 
-	// Initialize the node with the vector
+	// Initialize the node with the vector (as annoy, zero the children since the slot is reused)
+	var noChildren [2]TIX
+
+	node.SetChildren(noChildren[:])
 	node.SetNumberOfDescendants(1)
 	node.SetVector(v)
 	idx.distance.InitNode(node)
@@ -171,20 +330,51 @@ func (idx *AnnoyIndexImpl[TV, TIX]) AddItem(itemIndex TIX, v []TV) {
 		idx._n_items = itemIndex + 1
 	}
 
-	if idx.logVerbose {
-		fmt.Printf(
-			"added itemIndex:%d node - %s\n", itemIndex, utils.DumpNode(idx.distance, node),
-		)
-	}
+	idx.traceNode("added item", itemIndex, node)
+
+	return nil
 }
 
 func (idx *AnnoyIndexImpl[TV, TIX]) Build(numberOfTrees, numWorkers int) {
+	if err := idx.BuildE(numberOfTrees, numWorkers); err != nil {
+		panic(err.Error())
+	}
+}
+
+func (idx *AnnoyIndexImpl[TV, TIX]) BuildE(numberOfTrees, numWorkers int) error {
+	return idx.BuildContext(context.Background(), interfaces.BuildOptions{
+		NumberOfTrees: numberOfTrees,
+		NumWorkers:    numWorkers,
+	})
+}
+
+// BuildContext builds the index, see `interfaces.AnnoyIndex.BuildContext`. The index is
+// locked while building, hence the `BuildOptions.Progress` must not call back into the index.
+func (idx *AnnoyIndexImpl[TV, TIX]) BuildContext(
+	ctx context.Context,
+	opts interfaces.BuildOptions,
+) error {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+
+	return idx.buildContext(ctx, opts)
+}
+
+// buildContext is `BuildContext` without locking.
+func (idx *AnnoyIndexImpl[TV, TIX]) buildContext(
+	ctx context.Context,
+	opts interfaces.BuildOptions,
+) error {
+	if err := idx.checkAnnoyCompatibility(); err != nil {
+		return err
+	}
+
 	if idx.indexLoaded {
-		panic("Can't build a loaded index")
+		return fmt.Errorf("%w: can't build a loaded index", ErrIndexLoaded)
 	}
 
 	if idx.indexBuilt {
-		panic("Index already built")
+		return ErrAlreadyBuilt
 	}
 
 	// Give the preprocessor a chance to process the nodes before building the index
@@ -192,26 +382,71 @@ func (idx *AnnoyIndexImpl[TV, TIX]) Build(numberOfTrees, numWorkers int) {
 
 	idx._n_nodes = idx._n_items
 
-	idx.buildPolicy.Build(idx, numberOfTrees, numWorkers)
+	start := time.Now()
+
+	// Workers cancel the build with the error as cause when they fail to allocate memory
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	idx.buildProgress = buildProgressState{
+		progress:      opts.Progress,
+		numberOfTrees: opts.NumberOfTrees,
+		start:         start,
+		cancel:        cancel,
+	}
+
+	idx.numWorkers = opts.NumWorkers
+
+	idx.buildPolicy.Build(ctx, idx, opts.NumberOfTrees, opts.NumWorkers)
+
+	idx.buildProgress = buildProgressState{}
+
+	if ctx.Err() != nil {
+		// Cancelled or failed, drop the partially built forest
+		idx.dropTrees()
+		return context.Cause(ctx)
+	}
 
 	// Also, copy the roots into the last segment of the array
 	// This way we can load them faster without reading the whole file
-	idx.allocateSize(idx._n_nodes+TIX(len(idx._roots)), nil)
+	if err := idx.allocateSize(idx._n_nodes+TIX(len(idx._roots)), nil); err != nil {
+		idx.dropTrees()
+		return err
+	}
 
 	for i := TIX(0); i < TIX(len(idx._roots)); i++ {
 		dst := idx.getNode(idx._n_nodes + i)
 		src := idx.getNode(idx._roots[i])
 
-		utils.CopyNode(dst, src, idx.vectorLength)
+		utils.CopyNode(dst, src, idx.nodeSize)
 
-		if idx.logVerbose {
-			fmt.Printf(
-				"added roots[i=%d]:%d node - %s\n", i, idx._roots[i], utils.DumpNode(idx.distance, dst),
-			)
-		}
+		idx.traceNode("added root copy", idx._n_nodes+i, dst)
 	}
 
 	idx._n_nodes += TIX(len(idx._roots))
+
+	if fa, ok := idx.allocator.(interfaces.FileBuildIndexAllocator); ok {
+		// On disk build, shrink the file to the exact size of the index
+		nodes, err := fa.Truncate(int(idx._n_nodes * idx.nodeSize))
+		if err != nil {
+			// The file may have been unmapped, map it again before dropping the trees
+			if nodes, rerr := fa.Reallocate(int(idx._nodes_size * idx.nodeSize)); rerr == nil {
+				idx._nodes = nodes
+				idx.dropTrees()
+			} else {
+				idx._nodes = nil
+				idx._nodes_size = 0
+				idx._roots = nil
+				idx._n_nodes = idx._n_items
+			}
+
+			return err
+		}
+
+		idx._nodes = nodes
+		idx._nodes_size = idx._n_nodes
+	}
+
 	idx.indexBuilt = true
 
 	idx.batchMaxNNS = -1
@@ -228,13 +463,66 @@ func (idx *AnnoyIndexImpl[TV, TIX]) Build(numberOfTrees, numWorkers int) {
 		}
 	}
 
-	if idx.logVerbose {
-		fmt.Println("Max NNS:", idx.batchMaxNNS)
+	idx.logger.Info(
+		"index built",
+		"items", idx._n_items,
+		"nodes", idx._n_nodes,
+		"trees", len(idx._roots),
+		"elapsed", time.Since(start),
+	)
+
+	idx.logger.Debug("max nns", "batchMaxNNS", idx.batchMaxNNS)
+
+	return nil
+}
+
+// Unbuild drops all trees so that more items can be added and the index built again,
+// possibly with a different number of trees. The items are kept as is.
+func (idx *AnnoyIndexImpl[TV, TIX]) Unbuild() error {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+
+	if idx.indexLoaded {
+		return fmt.Errorf("%w: can't unbuild a loaded index", ErrIndexLoaded)
+	}
+
+	idx.dropTrees()
+
+	// Move any delta items into the index memory so they are part of the next build
+	for slot, item := range idx.delta.items {
+		if err := idx.addItem(item, idx.getDeltaNode(slot).GetVector(idx.vectorLength)); err != nil {
+			return err
+		}
+	}
+
+	idx.delta = deltaSegment[TIX]{}
+
+	return nil
+}
+
+// dropTrees removes all tree nodes and roots and leaves the items as is.
+func (idx *AnnoyIndexImpl[TV, TIX]) dropTrees() {
+	if idx._n_nodes > idx._n_items {
+		// Zero the tree nodes so that items added later starts out with clean memory
+		trees := unsafe.Slice(
+			(*byte)(unsafe.Add(idx._nodes, idx._n_items*idx.nodeSize)),
+			(idx._n_nodes-idx._n_items)*idx.nodeSize,
+		)
+
+		for i := range trees {
+			trees[i] = 0
+		}
 	}
+
+	idx._roots = nil
+	idx._n_nodes = idx._n_items
+	idx.indexBuilt = false
+	idx.batchMaxNNS = -1
 }
 
 // ThreadBuild is called from the build policy to build the index.
 func (idx *AnnoyIndexImpl[TV, TIX]) ThreadBuild(
+	ctx context.Context,
 	treesPerWorker, workerIdx int,
 	threadedBuildPolicy interfaces.AnnoyIndexBuildPolicy,
 ) {
@@ -246,6 +534,10 @@ func (idx *AnnoyIndexImpl[TV, TIX]) ThreadBuild(
 	var threadRoots []TIX
 
 	for {
+		if ctx.Err() != nil {
+			break
+		}
+
 		if treesPerWorker == -1 {
 			threadedBuildPolicy.LockNNodes()
 			if idx._n_nodes >= 2*idx._n_items {
@@ -273,10 +565,18 @@ func (idx *AnnoyIndexImpl[TV, TIX]) ThreadBuild(
 
 		threadedBuildPolicy.UnlockSharedNodes()
 
-		threadRoots = append(
-			threadRoots,
-			idx.makeTree(indices, true, rnd, threadedBuildPolicy),
-		)
+		root, err := idx.makeTree(indices, true, rnd, threadedBuildPolicy)
+		if err != nil {
+			if idx.buildProgress.cancel != nil {
+				idx.buildProgress.cancel(err)
+			}
+
+			break
+		}
+
+		threadRoots = append(threadRoots, root)
+
+		idx.reportProgress(threadedBuildPolicy)
 	}
 
 	threadedBuildPolicy.LockRoots()
@@ -284,15 +584,48 @@ func (idx *AnnoyIndexImpl[TV, TIX]) ThreadBuild(
 	threadedBuildPolicy.UnlockRoots()
 }
 
+// reportProgress counts a built tree and invokes the progress callback, if any.
+func (idx *AnnoyIndexImpl[TV, TIX]) reportProgress(
+	threadedBuildPolicy interfaces.AnnoyIndexBuildPolicy,
+) {
+	threadedBuildPolicy.LockRoots()
+	defer threadedBuildPolicy.UnlockRoots()
+
+	idx.buildProgress.treesBuilt++
+
+	if idx.buildProgress.progress == nil {
+		return
+	}
+
+	threadedBuildPolicy.LockNNodes()
+	nodes := idx._n_nodes
+	threadedBuildPolicy.UnlockNNodes()
+
+	idx.buildProgress.progress(interfaces.BuildProgress{
+		TreesBuilt:     idx.buildProgress.treesBuilt,
+		NumberOfTrees:  idx.buildProgress.numberOfTrees,
+		NodesAllocated: int(nodes),
+		Elapsed:        time.Since(idx.buildProgress.start),
+	})
+}
+
 func (idx *AnnoyIndexImpl[TV, TIX]) getNode(index TIX) interfaces.Node[TV, TIX] {
 	return idx.distance.MapNodeToMemory(idx._nodes, index)
 }
 
+// splitChildren returns the two children of the split node _nd_. Unlike `GetChildren`, that
+// uses the number of descendants as length, the slice never extends past the node memory.
+func splitChildren[TV interfaces.VectorType, TIX interfaces.IndexTypes](
+	nd interfaces.Node[TV, TIX],
+) []TIX {
+	return unsafe.Slice(nd.GetRawChildren(), 2)
+}
+
 func (idx *AnnoyIndexImpl[TV, TIX]) makeTree(
 	indices []TIX, isRoot bool,
 	rnd interfaces.Random[TIX],
 	threadedBuildPolicy interfaces.AnnoyIndexBuildPolicy,
-) TIX {
+) (TIX, error) {
 	// The basic rule is that if we have <= maxDescendants items, then it's a leaf node, otherwise it's a split node.
 	// There's some regrettable complications caused by the problem that root nodes have to be "special":
 	// 1. We identify root nodes by the arguable logic that _n_items == n->n_descendants,
@@ -302,7 +635,7 @@ func (idx *AnnoyIndexImpl[TV, TIX]) makeTree(
 	//
 	// 3. Due to the _n_items "hack", we need to be careful with the cases where _n_items <= _K or _n_items > _K
 	if len(indices) == 1 && !isRoot {
-		return indices[0]
+		return indices[0], nil
 	}
 
 	lenIdx := TIX(len(indices))
@@ -310,7 +643,10 @@ func (idx *AnnoyIndexImpl[TV, TIX]) makeTree(
 		(!isRoot || idx._n_items <= idx.maxDescendants || lenIdx == 1) {
 		// Ensure we have memory for the new node
 		threadedBuildPolicy.LockNNodes()
-		idx.allocateSize(idx._n_nodes+1, threadedBuildPolicy)
+		if err := idx.allocateSize(idx._n_nodes+1, threadedBuildPolicy); err != nil {
+			threadedBuildPolicy.UnlockNNodes()
+			return 0, err
+		}
 
 		item := idx._n_nodes
 		idx._n_nodes++
@@ -335,11 +671,9 @@ func (idx *AnnoyIndexImpl[TV, TIX]) makeTree(
 
 		threadedBuildPolicy.UnlockSharedNodes()
 
-		if idx.logVerbose {
-			fmt.Printf("added 1:node[item=%d] - %s\n", item, utils.DumpNode(idx.distance, m))
-		}
+		idx.traceNode("added leaf node", item, m)
 
-		return item
+		return item, nil
 	}
 
 	threadedBuildPolicy.LockSharedNodes()
@@ -363,7 +697,7 @@ func (idx *AnnoyIndexImpl[TV, TIX]) makeTree(
 		children_indices[0] = nil
 		children_indices[1] = nil
 
-		idx.distance.CreateSplit(children, idx.nodeSize, idx.random, m)
+		idx.distance.CreateSplit(children, idx.nodeSize, rnd, m)
 
 		for _, j := range indices {
 			// TODO: original code did a check: Node* n = _get(j); if (n) {...}
@@ -372,7 +706,7 @@ func (idx *AnnoyIndexImpl[TV, TIX]) makeTree(
 			side := idx.distance.Side(
 				m,
 				n.GetVector(idx.vectorLength),
-				idx.random,
+				rnd,
 			)
 
 			children_indices[side] = append(children_indices[side], j)
@@ -403,7 +737,7 @@ func (idx *AnnoyIndexImpl[TV, TIX]) makeTree(
 
 		for _, j := range indices {
 			// Just randomize...
-			side := idx.random.NextSide()
+			side := rnd.NextSide()
 			children_indices[side] = append(children_indices[side], j)
 		}
 	}
@@ -425,18 +759,27 @@ func (idx *AnnoyIndexImpl[TV, TIX]) makeTree(
 		// run makeTree for the smallest child first (for cache locality)
 		flip_side := side ^ flip
 
-		child_first[flip_side] = idx.makeTree(
+		child, err := idx.makeTree(
 			children_indices[flip_side],
 			false,
 			rnd,
 			threadedBuildPolicy,
 		)
+
+		if err != nil {
+			return 0, err
+		}
+
+		child_first[flip_side] = child
 	}
 
 	m.SetChildren(child_first)
 
 	idx.buildPolicy.LockNNodes()
-	idx.allocateSize(idx._n_nodes+1, threadedBuildPolicy)
+	if err := idx.allocateSize(idx._n_nodes+1, threadedBuildPolicy); err != nil {
+		idx.buildPolicy.UnlockNNodes()
+		return 0, err
+	}
 	item := idx._n_nodes
 	idx._n_nodes++
 	idx.buildPolicy.UnlockNNodes()
@@ -444,14 +787,12 @@ func (idx *AnnoyIndexImpl[TV, TIX]) makeTree(
 	idx.buildPolicy.LockSharedNodes()
 	dst := idx.getNode(item)
 
-	utils.CopyNode(dst, m, idx.vectorLength)
+	utils.CopyNode(dst, m, idx.nodeSize)
 	idx.buildPolicy.UnlockSharedNodes()
 
-	if idx.logVerbose {
-		fmt.Printf("added 2:node[item=%d] - %s\n", item, utils.DumpNode(idx.distance, dst))
-	}
+	idx.traceNode("added split node", item, dst)
 
-	return item
+	return item, nil
 }
 
 func (idx *AnnoyIndexImpl[TV, TIX]) splitImbalance(
@@ -466,19 +807,25 @@ func (idx *AnnoyIndexImpl[TV, TIX]) splitImbalance(
 func (idx *AnnoyIndexImpl[TV, TIX]) allocateSize(
 	numNodes TIX,
 	threadedBuildPolicy interfaces.AnnoyIndexBuildPolicy,
-) {
-	if numNodes > idx._nodes_size {
+) error {
+	if numNodes <= idx._nodes_size {
+		return nil
+	}
 
-		if threadedBuildPolicy != nil {
-			threadedBuildPolicy.LockNodes()
-		}
+	if threadedBuildPolicy != nil {
+		threadedBuildPolicy.LockNodes()
+		defer threadedBuildPolicy.UnlockNodes()
+	}
 
-		new_node_size := utils.Max(numNodes, TIX(float64(idx._nodes_size+1)*reallocation_factor))
-		idx._nodes = idx.allocator.Reallocate(int(new_node_size * idx.nodeSize))
-		idx._nodes_size = new_node_size
+	new_node_size := utils.Max(numNodes, TIX(float64(idx._nodes_size+1)*reallocation_factor))
 
-		if threadedBuildPolicy != nil {
-			threadedBuildPolicy.UnlockNodes()
-		}
+	nodes, err := idx.allocator.Reallocate(int(new_node_size * idx.nodeSize))
+	if err != nil {
+		return err
 	}
+
+	idx._nodes = nodes
+	idx._nodes_size = new_node_size
+
+	return nil
 }
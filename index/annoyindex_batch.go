@@ -0,0 +1,94 @@
+package index
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/mariotoffia/goannoy/interfaces"
+)
+
+// GetNnsByVectors searches for the closest items to each of the _vectors_ using up to
+// _workers_ goroutines. The results are returned in the same order as the _vectors_.
+//
+// When _workers_ is -1, it is set to the number of CPU cores. If 0, it is set to 1
+// and hence searches on the current goroutine. The contexts are managed internally.
+func (idx *AnnoyIndexImpl[TV, TIX]) GetNnsByVectors(
+	vectors [][]TV,
+	numReturn, numNodesToInspect, workers int,
+) (results [][]TIX, distances [][]TV) {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+
+	results = make([][]TIX, len(vectors))
+	distances = make([][]TV, len(vectors))
+
+	if workers == -1 {
+		workers = runtime.NumCPU()
+	} else if workers == 0 {
+		workers = 1
+	}
+
+	if workers > len(vectors) {
+		workers = len(vectors)
+	}
+
+	if workers <= 1 {
+		bc := idx.acquireContext()
+		defer idx.releaseContext(bc)
+
+		for i := range vectors {
+			results[i], distances[i] = idx.search(
+				vectors[i], numReturn, numNodesToInspect, bc, interfaces.SearchOptions[TIX]{},
+			)
+		}
+
+		return
+	}
+
+	var wg sync.WaitGroup
+
+	queue := make(chan int, len(vectors))
+
+	for i := range vectors {
+		queue <- i
+	}
+
+	close(queue)
+
+	wg.Add(workers)
+
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+
+			bc := idx.acquireContext()
+			defer idx.releaseContext(bc)
+
+			for i := range queue {
+				results[i], distances[i] = idx.search(
+					vectors[i], numReturn, numNodesToInspect, bc, interfaces.SearchOptions[TIX]{},
+				)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return
+}
+
+// acquireContext returns a pooled `BatchContext` or creates a new one.
+func (idx *AnnoyIndexImpl[TV, TIX]) acquireContext() *BatchContext[TV, TIX] {
+	if bc, ok := idx.contexts.Get().(*BatchContext[TV, TIX]); ok && bc.length == idx.contextLength() {
+		return bc
+	}
+
+	// Either empty pool or the index has been built, loaded or closed since the
+	// context was created
+	return idx.createContext()
+}
+
+// releaseContext returns the _bc_ to the pool.
+func (idx *AnnoyIndexImpl[TV, TIX]) releaseContext(bc *BatchContext[TV, TIX]) {
+	idx.contexts.Put(bc)
+}
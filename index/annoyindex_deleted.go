@@ -0,0 +1,166 @@
+package index
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/mariotoffia/goannoy/utils"
+)
+
+// deletedSuffix is appended to the index file name to get the deletion bitmap sidecar file.
+const deletedSuffix = ".del"
+
+// MarkDeleted marks the _items_ as deleted and hence they are skipped when searching. The items
+// are purged from the trees when `Compact` is invoked. If any of the items do not exist, none
+// of them are marked.
+//
+// When the index is loaded, the whole deletion bitmap is written to the `<file>.del` sidecar at
+// once, on each invocation, otherwise it is written when the index is saved. Hence, pass all
+// items to delete in one invocation rather than one invocation per item.
+func (idx *AnnoyIndexImpl[TV, TIX]) MarkDeleted(items ...TIX) error {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+
+	if !idx.indexBuilt {
+		return fmt.Errorf("%w: can only delete items in a built or loaded index", ErrNotBuilt)
+	}
+
+	for _, item := range items {
+		if item >= idx.numberOfItems() {
+			return fmt.Errorf("item %d do not exist", item)
+		}
+	}
+
+	if idx.deleted == nil {
+		idx.deleted = utils.NewBitset(idx.numberOfItems())
+	}
+
+	for _, item := range items {
+		idx.deleted.Set(item)
+	}
+
+	if idx.indexLoaded && idx.loadedFile != "" {
+		return idx.saveDeleted(idx.loadedFile)
+	}
+
+	return nil
+}
+
+// undelete clears the deleted mark of _item_, e.g. when it is added again.
+func (idx *AnnoyIndexImpl[TV, TIX]) undelete(item TIX) {
+	if idx.deleted != nil {
+		idx.deleted.Clear(item)
+	}
+}
+
+// IsDeleted returns `true` if the _item_ has been marked as deleted.
+func (idx *AnnoyIndexImpl[TV, TIX]) IsDeleted(item TIX) bool {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+
+	return idx.isDeleted(item)
+}
+
+// isDeleted is `IsDeleted` without locking.
+func (idx *AnnoyIndexImpl[TV, TIX]) isDeleted(item TIX) bool {
+	return idx.deleted != nil && idx.deleted.Contains(item)
+}
+
+// DeletedCount returns the number of items marked as deleted but not yet purged by `Compact`.
+func (idx *AnnoyIndexImpl[TV, TIX]) DeletedCount() int {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+
+	return idx.deletedCount()
+}
+
+// deletedCount is `DeletedCount` without locking.
+func (idx *AnnoyIndexImpl[TV, TIX]) deletedCount() int {
+	if idx.deleted == nil {
+		return 0
+	}
+
+	return idx.deleted.Count()
+}
+
+// searchFilter combines the _filter_ with the deleted items. It returns `nil` if there's
+// nothing to filter.
+func (idx *AnnoyIndexImpl[TV, TIX]) searchFilter(filter func(item TIX) bool) func(item TIX) bool {
+	if idx.deleted == nil || idx.deleted.Count() == 0 {
+		return filter
+	}
+
+	if filter == nil {
+		return idx.notDeleted
+	}
+
+	return func(item TIX) bool {
+		return !idx.deleted.Contains(item) && filter(item)
+	}
+}
+
+// purgeDeleted removes the deleted items from the index memory so they are not part of the
+// next build. The index must not be built.
+func (idx *AnnoyIndexImpl[TV, TIX]) purgeDeleted() {
+	if idx.deleted == nil {
+		return
+	}
+
+	for i := TIX(0); i < idx._n_items; i++ {
+		if idx.deleted.Contains(i) {
+			// Trees are only built from nodes having descendants
+			idx.getNode(i).SetNumberOfDescendants(0)
+		}
+	}
+}
+
+// saveDeleted writes the deletion bitmap to the sidecar of _fileName_. If there are no deleted
+// items any existing sidecar is removed.
+func (idx *AnnoyIndexImpl[TV, TIX]) saveDeleted(fileName string) error {
+	sidecar := fileName + deletedSuffix
+
+	if idx.deletedCount() == 0 {
+		if err := os.Remove(sidecar); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+
+		return nil
+	}
+
+	return utils.WriteFileAtomic(sidecar, func(w io.Writer) error {
+		_, err := idx.deleted.WriteTo(w)
+		return err
+	})
+}
+
+// loadDeleted reads the deletion bitmap from the sidecar of _fileName_, if it exists. The
+// sidecar is opened using _open_.
+func (idx *AnnoyIndexImpl[TV, TIX]) loadDeleted(
+	fileName string,
+	open func(name string) (fs.File, error),
+) error {
+	file, err := open(fileName + deletedSuffix)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+
+		return err
+	}
+
+	defer file.Close()
+
+	deleted := &utils.Bitset[TIX]{}
+
+	if _, err := deleted.ReadFromLimited(bufio.NewReader(file), idx._n_items); err != nil {
+		return fmt.Errorf("failed to read deleted items from %s: %w", fileName+deletedSuffix, err)
+	}
+
+	idx.deleted = deleted
+
+	return nil
+}
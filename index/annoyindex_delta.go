@@ -0,0 +1,198 @@
+package index
+
+import (
+	"context"
+	"fmt"
+	"unsafe"
+
+	"github.com/mariotoffia/goannoy/interfaces"
+)
+
+// deltaSegment holds the items added after the index has been built when in incremental
+// mode. The items are searched using brute force alongside the trees until `Compact` is
+// invoked.
+type deltaSegment[TIX interfaces.IndexTypes] struct {
+	// nodes is the memory of all nodes, each slot is a node size large.
+	nodes []byte
+	// items is the item index of each slot.
+	items []TIX
+	// slots maps an item index to the slot in _nodes_.
+	slots map[TIX]int
+}
+
+// WithIncrementalInserts makes it possible to add items after the index has been built or
+// loaded. Those items are kept in a delta segment that is searched using brute force alongside
+// the trees. An item added with the same index as an item in the trees, shadows that item.
+//
+// Use `Compact` to rebuild the trees including the delta items.
+func WithIncrementalInserts[TV interfaces.VectorType, TIX interfaces.IndexTypes]() Option[TV, TIX] {
+	return func(idx *AnnoyIndexImpl[TV, TIX]) {
+		idx.incremental = true
+	}
+}
+
+// Compact rebuilds the trees, using the same number of trees and workers as the last build, including
+// all items in the delta segment and excluding all items marked as deleted. If the index has
+// been loaded from a file, the compacted index is saved back to that file, and hence the
+// `<file>.del` sidecar is removed, since the deleted items are written to the sidecar at once.
+// Otherwise, the index needs to be saved again.
+func (idx *AnnoyIndexImpl[TV, TIX]) Compact() error {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+
+	if !idx.incremental && idx.deletedCount() == 0 {
+		return fmt.Errorf(
+			"%w, the index is not incremental and has no deleted items", ErrNothingToCompact,
+		)
+	}
+
+	if !idx.indexBuilt {
+		return fmt.Errorf("%w: can't compact an index that hasn't been built", ErrNotBuilt)
+	}
+
+	numberOfTrees := len(idx._roots)
+	loadedFile := idx.loadedFile
+
+	if idx.indexLoaded {
+		if err := idx.detachLoaded(); err != nil {
+			return err
+		}
+	} else {
+		idx.dropTrees()
+	}
+
+	idx.purgeDeleted()
+
+	for slot, item := range idx.delta.items {
+		if idx.isDeleted(item) {
+			continue
+		}
+
+		if err := idx.addItem(item, idx.getDeltaNode(slot).GetVector(idx.vectorLength)); err != nil {
+			return err
+		}
+	}
+
+	idx.delta = deltaSegment[TIX]{}
+	idx.deleted = nil
+
+	if err := idx.buildContext(context.Background(), interfaces.BuildOptions{
+		NumberOfTrees: numberOfTrees,
+		NumWorkers:    idx.numWorkers,
+	}); err != nil {
+		return err
+	}
+
+	if loadedFile != "" {
+		return idx.save(loadedFile)
+	}
+
+	return nil
+}
+
+// detachLoaded copies all items of the loaded index into the build memory and releases
+// the loaded index memory. The index is left unbuilt.
+func (idx *AnnoyIndexImpl[TV, TIX]) detachLoaded() error {
+	src, srcSize := idx._nodes, idx._nodes_size
+	numItems := idx._n_items
+
+	idx._nodes = nil
+	idx._nodes_size = 0
+
+	if err := idx.allocateSize(numItems, nil); err != nil {
+		idx._nodes, idx._nodes_size = src, srcSize
+		return err
+	}
+
+	copy(
+		unsafe.Slice((*byte)(idx._nodes), numItems*idx.nodeSize),
+		unsafe.Slice((*byte)(src), numItems*idx.nodeSize),
+	)
+
+	if idx.indexMemory != nil {
+		idx.indexMemory.Close()
+		idx.indexMemory = nil
+	}
+
+	idx.indexLoaded = false
+	idx._roots = nil
+	idx._n_nodes = numItems
+	idx.indexBuilt = false
+	idx.batchMaxNNS = -1
+
+	return nil
+}
+
+// addDeltaItem adds, or replaces, the item in the delta segment.
+func (idx *AnnoyIndexImpl[TV, TIX]) addDeltaItem(itemIndex TIX, v []TV) {
+	slot, ok := idx.delta.slots[itemIndex]
+
+	if !ok {
+		if idx.delta.slots == nil {
+			idx.delta.slots = map[TIX]int{}
+		}
+
+		slot = len(idx.delta.items)
+
+		idx.delta.slots[itemIndex] = slot
+		idx.delta.items = append(idx.delta.items, itemIndex)
+		idx.delta.nodes = append(idx.delta.nodes, make([]byte, idx.nodeSize)...)
+	}
+
+	node := idx.getDeltaNode(slot)
+
+	node.SetNumberOfDescendants(1)
+	node.SetVector(v)
+	idx.distance.InitNode(node)
+
+	idx.traceNode("added delta item", itemIndex, node)
+}
+
+// getDeltaNode maps the node in the delta segment _slot_.
+func (idx *AnnoyIndexImpl[TV, TIX]) getDeltaNode(slot int) interfaces.Node[TV, TIX] {
+	return idx.distance.MapNodeToMemory(unsafe.Pointer(unsafe.SliceData(idx.delta.nodes)), TIX(slot))
+}
+
+// itemNode maps the node of _item_, in the delta segment if added or replaced after build.
+func (idx *AnnoyIndexImpl[TV, TIX]) itemNode(item TIX) interfaces.Node[TV, TIX] {
+	if slot, ok := idx.delta.slots[item]; ok {
+		return idx.getDeltaNode(slot)
+	}
+
+	return idx.getNode(item)
+}
+
+// isShadowed returns `true` if the tree _item_ has been replaced by an item in the delta segment.
+func (idx *AnnoyIndexImpl[TV, TIX]) isShadowed(item TIX) bool {
+	_, ok := idx.delta.slots[item]
+	return ok
+}
+
+// deltaCandidates calculates the distance from _v_node_ to each delta item passing the
+// _filter_ and stores them in the _bc_ pairs starting at _cnt_. It returns the new count.
+func (idx *AnnoyIndexImpl[TV, TIX]) deltaCandidates(
+	v_node interfaces.Node[TV, TIX],
+	bc *BatchContext[TV, TIX],
+	cnt int,
+	filter func(item TIX) bool,
+) int {
+	if len(idx.delta.items) == 0 {
+		return cnt
+	}
+
+	bc.ensurePairs(cnt + len(idx.delta.items))
+
+	for slot, item := range idx.delta.items {
+		if filter != nil && !filter(item) {
+			continue
+		}
+
+		pair := bc.nns_dist[cnt]
+		pair.First = idx.distance.Distance(v_node, idx.getDeltaNode(slot))
+		pair.Second = item
+
+		cnt++
+	}
+
+	return cnt
+}
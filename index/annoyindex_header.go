@@ -0,0 +1,160 @@
+package index
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"unsafe"
+)
+
+// headerVersion is the current version of the file header.
+const headerVersion = uint32(1)
+
+// headerMagic is the first bytes of a file that has a `fileHeader`.
+var headerMagic = [8]byte{'G', 'O', 'A', 'N', 'N', 'O', 'Y', 0}
+
+// fileHeader is an optional, self describing, header that is written in front of
+// the nodes when saving an index. It is used to validate that the file is loaded
+// into an index with the same configuration.
+//
+// The header is always written in little endian and its size is a multiple of eight
+// so the nodes that follows it are aligned when the file is memory mapped.
+type fileHeader struct {
+	Magic   [8]byte
+	Version uint32
+	// HeaderSize is the size of the header in bytes, i.e. the offset to the first node.
+	HeaderSize uint32
+	// Metric is the zero padded name of the distance used.
+	Metric       [16]byte
+	VectorLength uint64
+	// ElementSize is the size of TV in bytes.
+	ElementSize uint32
+	// IndexSize is the size of TIX in bytes.
+	IndexSize uint32
+	NodeSize  uint64
+	ItemCount uint64
+	RootCount uint64
+	// Checksum is the CRC-32 (IEEE) of all nodes following the header.
+	Checksum uint32
+	_        uint32
+}
+
+var headerSize = uint32(binary.Size(fileHeader{}))
+
+// createHeader creates a header describing the current state of the index.
+func (idx *AnnoyIndexImpl[TV, TIX]) createHeader(data []byte) *fileHeader {
+	hdr := &fileHeader{
+		Magic:        headerMagic,
+		Version:      headerVersion,
+		HeaderSize:   headerSize,
+		VectorLength: uint64(idx.vectorLength),
+		ElementSize:  uint32(unsafe.Sizeof(TV(0))),
+		IndexSize:    uint32(unsafe.Sizeof(TIX(0))),
+		NodeSize:     uint64(idx.nodeSize),
+		ItemCount:    uint64(idx._n_items),
+		RootCount:    uint64(len(idx._roots)),
+		Checksum:     crc32.ChecksumIEEE(data),
+	}
+
+	copy(hdr.Metric[:], idx.distance.Name())
+
+	return hdr
+}
+
+// readHeader reads the header from _mem_ if present. If the memory do not start
+// with `headerMagic`, `nil` is returned.
+func readHeader(mem unsafe.Pointer, size int64) (*fileHeader, error) {
+	if size < int64(len(headerMagic)) {
+		return nil, nil
+	}
+
+	if !bytes.Equal(unsafe.Slice((*byte)(mem), len(headerMagic)), headerMagic[:]) {
+		return nil, nil
+	}
+
+	if size < int64(headerSize) {
+		return nil, fmt.Errorf("%w: file is smaller than the header", ErrInvalidHeader)
+	}
+
+	var hdr fileHeader
+
+	err := binary.Read(
+		bytes.NewReader(unsafe.Slice((*byte)(mem), headerSize)),
+		binary.LittleEndian,
+		&hdr,
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidHeader, err.Error())
+	}
+
+	if hdr.Version == 0 || hdr.Version > headerVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidHeader, hdr.Version)
+	}
+
+	if hdr.HeaderSize < headerSize || hdr.HeaderSize%8 != 0 || int64(hdr.HeaderSize) > size {
+		return nil, fmt.Errorf("%w: bad header size %d", ErrInvalidHeader, hdr.HeaderSize)
+	}
+
+	return &hdr, nil
+}
+
+// validateHeader checks that the _hdr_ matches the configuration of this index and that
+// the _data_ (all nodes after the header) is intact.
+func (idx *AnnoyIndexImpl[TV, TIX]) validateHeader(hdr *fileHeader, data []byte) error {
+	metric := string(bytes.TrimRight(hdr.Metric[:], "\x00"))
+
+	if metric != idx.distance.Name() {
+		return fmt.Errorf(
+			"%w: metric is %q, index uses %q", ErrIncompatibleIndex, metric, idx.distance.Name(),
+		)
+	}
+
+	if hdr.VectorLength != uint64(idx.vectorLength) {
+		return fmt.Errorf(
+			"%w: vector length is %d, index uses %d",
+			ErrIncompatibleIndex, hdr.VectorLength, idx.vectorLength,
+		)
+	}
+
+	if es := uint32(unsafe.Sizeof(TV(0))); hdr.ElementSize != es {
+		return fmt.Errorf(
+			"%w: vector element size is %d bytes, index uses %d bytes",
+			ErrIncompatibleIndex, hdr.ElementSize, es,
+		)
+	}
+
+	if is := uint32(unsafe.Sizeof(TIX(0))); hdr.IndexSize != is {
+		return fmt.Errorf(
+			"%w: index size is %d bytes, index uses %d bytes",
+			ErrIncompatibleIndex, hdr.IndexSize, is,
+		)
+	}
+
+	if hdr.NodeSize != uint64(idx.nodeSize) {
+		return fmt.Errorf(
+			"%w: node size is %d bytes, index uses %d bytes",
+			ErrIncompatibleIndex, hdr.NodeSize, idx.nodeSize,
+		)
+	}
+
+	numNodes := uint64(len(data)) / hdr.NodeSize
+
+	if uint64(len(data))%hdr.NodeSize != 0 ||
+		hdr.RootCount == 0 ||
+		hdr.ItemCount+hdr.RootCount > numNodes {
+		return fmt.Errorf(
+			"%w: file holds %d bytes of nodes, expected %d items and %d roots",
+			ErrInvalidHeader, len(data), hdr.ItemCount, hdr.RootCount,
+		)
+	}
+
+	if crc := crc32.ChecksumIEEE(data); crc != hdr.Checksum {
+		return fmt.Errorf(
+			"%w: checksum mismatch %08x != %08x", ErrInvalidHeader, crc, hdr.Checksum,
+		)
+	}
+
+	return nil
+}
@@ -1,103 +1,283 @@
 package index
 
 import (
+	"encoding/binary"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"unsafe"
 
+	"github.com/mariotoffia/goannoy/index/memory"
+	"github.com/mariotoffia/goannoy/interfaces"
 	"github.com/mariotoffia/goannoy/utils"
 )
 
+// Save writes the index to _fileName_ and loads it. The index is written to a temporary file
+// that is synced and renamed to _fileName_, hence readers never see a partially written file.
 func (idx *AnnoyIndexImpl[TV, TIX]) Save(fileName string) error {
-	if !idx.indexBuilt {
-		return fmt.Errorf("can't save an index that hasn't been built")
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+
+	return idx.save(fileName)
+}
+
+// save is `Save` without locking.
+func (idx *AnnoyIndexImpl[TV, TIX]) save(fileName string) error {
+	if err := idx.canSave(); err != nil {
+		return err
 	}
 
-	file, err := os.Create(fileName)
-	if err != nil {
+	if fa, ok := idx.allocator.(interfaces.FileBuildIndexAllocator); ok && fa.FileName() == fileName {
+		// On disk build, the nodes are already in the file
+		if idx.fileHeader {
+			return fmt.Errorf(
+				"can't write a file header in place of the on disk build file %s", fileName,
+			)
+		}
+
+		if err := idx.saveDeleted(fileName); err != nil {
+			return err
+		}
+
+		return idx.load(fileName)
+	}
+
+	idx.logger.Info("saving index", "file", fileName, "nodes", idx._n_nodes)
+
+	if err := utils.WriteFileAtomic(fileName, idx.saveTo); err != nil {
+		return err
+	}
+
+	if err := idx.saveDeleted(fileName); err != nil {
+		return err
+	}
+
+	return idx.load(fileName)
+}
+
+// SaveTo writes the index, as `Save` does, to _w_. Unlike `Save`, the index is not loaded
+// afterwards and the deleted items are not written.
+func (idx *AnnoyIndexImpl[TV, TIX]) SaveTo(w io.Writer) error {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+
+	if err := idx.canSave(); err != nil {
+		return err
+	}
+
+	return idx.saveTo(w)
+}
+
+// canSave returns an error if the index is not in a state to be saved.
+func (idx *AnnoyIndexImpl[TV, TIX]) canSave() error {
+	if err := idx.checkAnnoyCompatibility(); err != nil {
 		return err
 	}
 
-	defer file.Close()
+	if !idx.indexBuilt {
+		return fmt.Errorf("%w: can't save an index that hasn't been built", ErrNotBuilt)
+	}
 
-	if idx.logVerbose {
-		fmt.Println("Saving index to file", fileName, "with", idx._n_nodes, "nodes")
+	if len(idx.delta.items) > 0 {
+		return fmt.Errorf("%w: compact before saving", ErrNotCompacted)
+	}
+
+	return nil
+}
+
+// saveTo writes the optional file header and the nodes to _w_.
+func (idx *AnnoyIndexImpl[TV, TIX]) saveTo(w io.Writer) error {
+	if idx.traceEnabled() {
 		for i := TIX(0); i < idx._n_nodes; i++ {
-			n := idx.getNode(i)
-			fmt.Println(i, ": ", utils.DumpNode(idx.distance, n))
+			idx.traceNode("saving node", i, idx.getNode(i))
 		}
 	}
 
 	data := unsafe.Slice((*byte)(idx._nodes), idx._n_nodes*idx.nodeSize)
 
-	_, err = file.Write(data)
+	if idx.fileHeader {
+		if err := binary.Write(w, binary.LittleEndian, idx.createHeader(data)); err != nil {
+			return err
+		}
+	}
+
+	_, err := w.Write(data)
+
+	return err
+}
+
+// Load loads the index from _fileName_. It waits for ongoing searches to finish before the
+// current index is closed.
+func (idx *AnnoyIndexImpl[TV, TIX]) Load(fileName string) error {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+
+	return idx.load(fileName)
+}
+
+// load is `Load` without locking.
+func (idx *AnnoyIndexImpl[TV, TIX]) load(fileName string) error {
+	// Close any existing index and free resources
+	idx.close()
+
+	mem, err := idx.indexMemoryAllocator.Open(fileName)
 
 	if err != nil {
 		return err
 	}
 
-	return idx.Load(fileName)
+	if err := idx.loadAllocated(mem, fileName, openFile); err != nil {
+		return err
+	}
+
+	idx.loadedFile = fileName
+
+	return nil
 }
 
-func (idx *AnnoyIndexImpl[TV, TIX]) Load(fileName string) error {
-	// Close any existing index and free resources
-	idx.Close()
+// LoadBytes loads the index from _data_, e.g. embedded using `//go:embed`. The _data_ is used
+// in place when suitably aligned and hence must not be modified while loaded.
+func (idx *AnnoyIndexImpl[TV, TIX]) LoadBytes(data []byte) error {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
 
-	var err error
+	idx.close()
 
-	idx.indexMemory, err = idx.indexMemoryAllocator.Open(fileName)
+	mem, err := memory.BytesIndex(data)
+	if err != nil {
+		return err
+	}
+
+	return idx.loadAllocated(mem, "", nil)
+}
+
+// LoadReaderAt reads _size_ bytes of index from _r_ into memory and loads it.
+func (idx *AnnoyIndexImpl[TV, TIX]) LoadReaderAt(r io.ReaderAt, size int64) error {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
 
+	idx.close()
+
+	mem, err := memory.ReaderAtIndex(r, size)
 	if err != nil {
 		return err
 	}
 
-	if idx.indexMemory.Size()%int64(idx.nodeSize) != 0 {
-		idx.Close()
+	return idx.loadAllocated(mem, "", nil)
+}
+
+// LoadFS loads the index file _name_, and its `<name>.del` sidecar if present, from _fsys_,
+// e.g. an `embed.FS`. Since the file may be read only, deletions are not written back to the
+// sidecar.
+func (idx *AnnoyIndexImpl[TV, TIX]) LoadFS(fsys fs.FS, name string) error {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
 
-		return fmt.Errorf("file size is not a multiple of node size")
+	idx.close()
+
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return err
 	}
 
-	idx._nodes = idx.indexMemory.Ptr()
-	idx._roots = nil
-	idx._n_nodes = TIX(idx.indexMemory.Size()) / idx.nodeSize
+	mem, err := memory.BytesIndex(data)
+	if err != nil {
+		return err
+	}
 
-	var (
-		mset bool
-		m    TIX
-	)
+	return idx.loadAllocated(mem, name, fsys.Open)
+}
 
-	for i := idx._n_nodes - 1; i >= 0; i-- {
+// openFile opens a sidecar file from the file system.
+func openFile(name string) (fs.File, error) {
+	return os.Open(name)
+}
 
-		n := idx.getNode(i)
-		k := n.GetNumberOfDescendants()
+// loadAllocated loads the index from the _mem_ that has been read from _name_, the _name_ is
+// empty when not read from a file. The _open_ is used to open any sidecars, when `nil` there
+// are no sidecars. The index must be closed.
+func (idx *AnnoyIndexImpl[TV, TIX]) loadAllocated(
+	mem interfaces.AllocatedIndex,
+	name string,
+	open func(name string) (fs.File, error),
+) error {
+	idx.indexMemory = mem
 
-		if !mset || k == m {
-			idx._roots = append(idx._roots, i)
-			m = k
-			mset = true
-		} else {
-			break
+	if err := idx.checkAnnoyCompatibility(); err != nil {
+		idx.close()
+
+		return err
+	}
+
+	nodes := idx.indexMemory.Ptr()
+	size := idx.indexMemory.Size()
+
+	hdr, err := readHeader(nodes, size)
+
+	if err != nil {
+		idx.close()
+
+		return err
+	}
+
+	if hdr != nil && idx.annoyCompatible {
+		idx.close()
+
+		return fmt.Errorf("%w: the file has a header", ErrNotAnnoyCompatible)
+	}
+
+	if hdr != nil {
+		nodes = unsafe.Add(nodes, hdr.HeaderSize)
+		size -= int64(hdr.HeaderSize)
+
+		if err := idx.validateHeader(hdr, unsafe.Slice((*byte)(nodes), size)); err != nil {
+			idx.close()
+
+			return err
 		}
 	}
 
-	// hacky fix: since the last root precedes the copy of all roots, delete it
-	if len(idx._roots) > 1 {
-		fn := idx.getNode(idx._roots[0])
-		ln := idx.getNode(idx._roots[len(idx._roots)-1])
+	if size%int64(idx.nodeSize) != 0 {
+		idx.close()
 
-		if fn.GetChildren()[0] == ln.GetChildren()[0] {
-			idx._roots = idx._roots[:len(idx._roots)-1]
+		return fmt.Errorf("file size is not a multiple of node size")
+	}
+
+	idx._nodes = nodes
+	idx._roots = nil
+	idx._n_nodes = TIX(size) / idx.nodeSize
+
+	var m TIX
+
+	if hdr != nil {
+		// The header tells exactly where the copies of the roots are
+		m = TIX(hdr.ItemCount)
+
+		for i := TIX(0); i < TIX(hdr.RootCount); i++ {
+			idx._roots = append(idx._roots, idx._n_nodes-1-i)
 		}
+	} else {
+		m = idx.findRoots()
 	}
 
 	idx.indexBuilt = true
 	idx.indexLoaded = true
 	idx._n_items = m
 
-	if idx.logVerbose {
-		fmt.Println("Loaded index to from file", fileName, "with", idx._n_nodes, "nodes")
+	if open != nil {
+		if err := idx.loadDeleted(name, open); err != nil {
+			idx.close()
+
+			return err
+		}
 	}
 
+	idx.logger.Info(
+		"loaded index", "file", name, "items", idx._n_items, "nodes", idx._n_nodes,
+		"trees", len(idx._roots),
+	)
+
 	idx.batchMaxNNS = -1
 
 	for i := TIX(0); i < idx._n_nodes; i++ {
@@ -111,14 +291,46 @@ func (idx *AnnoyIndexImpl[TV, TIX]) Load(fileName string) error {
 			idx.batchMaxNNS += len(nd.GetChildren())
 		}
 
-		if idx.logVerbose {
-			fmt.Println(i, ": ", utils.DumpNode(idx.distance, nd))
+		idx.traceNode("loaded node", i, nd)
+	}
+
+	idx.logger.Debug("max nns", "batchMaxNNS", idx.batchMaxNNS)
+
+	return nil
+}
+
+// findRoots will find the roots by scanning backwards from the end of the nodes. This is
+// used when the file do not have a header. It returns the number of items in the index.
+func (idx *AnnoyIndexImpl[TV, TIX]) findRoots() TIX {
+	var (
+		mset bool
+		m    TIX
+	)
+
+	// TIX is unsigned, hence loop down to one and use i-1 as node index
+	for i := idx._n_nodes; i > 0; i-- {
+
+		n := idx.getNode(i - 1)
+		k := n.GetNumberOfDescendants()
+
+		if !mset || k == m {
+			idx._roots = append(idx._roots, i-1)
+			m = k
+			mset = true
+		} else {
+			break
 		}
 	}
 
-	if idx.logVerbose {
-		fmt.Println("Max NNS:", idx.batchMaxNNS)
+	// hacky fix: since the last root precedes the copy of all roots, delete it
+	if len(idx._roots) > 1 {
+		fn := idx.getNode(idx._roots[0])
+		ln := idx.getNode(idx._roots[len(idx._roots)-1])
+
+		if splitChildren(fn)[0] == splitChildren(ln)[0] {
+			idx._roots = idx._roots[:len(idx._roots)-1]
+		}
 	}
 
-	return nil
+	return m
 }
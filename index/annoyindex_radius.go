@@ -0,0 +1,126 @@
+package index
+
+import "github.com/mariotoffia/goannoy/interfaces"
+
+// GetNnsWithinRadius returns all items whose normalized distance to the _vector_ is at most
+// _radius_, sorted by distance.
+//
+// When the distance implements `interfaces.MarginBounder`, e.g. euclidean and manhattan, the
+// sub-trees on the far side of a split plane, further away than _radius_, are pruned. For other
+// distances all sub-trees are inspected. When _numNodesToInspect_ is -1, there's no limit on the
+// number of candidate items to inspect, otherwise the search stops when that many candidates
+// have been inspected and hence may miss items.
+func (idx *AnnoyIndexImpl[TV, TIX]) GetNnsWithinRadius(
+	vector []TV,
+	radius TV,
+	numNodesToInspect int,
+	ctx interfaces.AnnoyIndexContext[TV, TIX],
+) (result []TIX, distances []TV) {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+
+	if idx._nodes == nil {
+		// Closed
+		return nil, nil
+	}
+
+	bc := ctx.(*BatchContext[TV, TIX])
+	bc.ensureLength(idx.contextLength())
+
+	bounder, prune := idx.distance.(interfaces.MarginBounder[TV])
+
+	stack := make([]TIX, 0, 2*len(idx._roots))
+	stack = append(stack, idx._roots...)
+
+	inspected, cnt := 0, 0
+
+	for len(stack) > 0 && (numNodesToInspect == -1 || inspected < numNodesToInspect) {
+		i := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		nd := idx.distance.MapNodeToMemory(idx._nodes, i)
+		nDescendants := nd.GetNumberOfDescendants()
+
+		if nDescendants == 1 && i < idx._n_items {
+			cnt = bc.addCandidates(cnt, i)
+			inspected++
+		} else if nDescendants <= idx.maxDescendants {
+			cnt = bc.addCandidates(cnt, nd.GetChildren()[:nDescendants]...)
+			inspected += int(nDescendants)
+		} else {
+			margin := idx.distance.Margin(nd, vector)
+			children := splitChildren(nd)
+
+			near, far := children[interfaces.SideRight], children[interfaces.SideLeft]
+			if margin < 0 {
+				near, far = far, near
+			}
+
+			// A split that fell back to random sides has a zero normal and hence the
+			// margin says nothing about the distance to the far side.
+			if !prune || isZeroVector(nd.GetVector(idx.vectorLength)) ||
+				bounder.MarginBound(margin) <= radius {
+				stack = append(stack, far)
+			}
+
+			// Push near last so it is inspected first
+			stack = append(stack, near)
+		}
+	}
+
+	nns := bc.nns[:dedupe(bc.nns[:cnt], idx.sorter)]
+
+	v_node := idx.queryNode(vector, false, bc)
+
+	cnt = 0
+
+	for _, j := range nns {
+		n := idx.distance.MapNodeToMemory(idx._nodes, j)
+
+		if n.GetNumberOfDescendants() != 1 || idx.isShadowed(j) || idx.isDeleted(j) {
+			continue
+		}
+
+		d := idx.distance.Distance(v_node, n)
+
+		if idx.distance.NormalizedDistance(d) <= radius {
+			pair := bc.nns_dist[cnt]
+			pair.First = d
+			pair.Second = j
+
+			cnt++
+		}
+	}
+
+	// Brute force the delta items and drop those outside the radius
+	end := idx.deltaCandidates(v_node, bc, cnt, idx.searchFilter(nil))
+
+	for i := cnt; i < end; i++ {
+		if idx.distance.NormalizedDistance(bc.nns_dist[i].First) <= radius {
+			bc.nns_dist[cnt], bc.nns_dist[i] = bc.nns_dist[i], bc.nns_dist[cnt]
+			cnt++
+		}
+	}
+
+	nns_dist := bc.nns_dist[:cnt]
+
+	idx.sorter.SortPairs(nns_dist)
+
+	for _, pair := range nns_dist {
+		distances = append(distances, idx.distance.NormalizedDistance(pair.First))
+		result = append(result, pair.Second)
+	}
+
+	return
+}
+
+// isZeroVector returns `true` if all elements of _v_ are zero.
+func isZeroVector[TV interfaces.VectorType](v []TV) bool {
+	for _, e := range v {
+		if e != 0 {
+			return false
+		}
+	}
+
+	return true
+}
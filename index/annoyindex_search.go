@@ -13,20 +13,30 @@ type BatchContext[TV interfaces.VectorType, TIX interfaces.IndexTypes] struct {
 	nns      []TIX
 	nns_dist []*interfaces.Pair[TV, TIX]
 	length   int
+	// pq is the priority queue reused between searches.
+	pq sort.PairHeap[TV, TIX]
+	// queryNode is the memory of the node holding the vector to search for.
+	queryNode []byte
 }
 
 // CreateContext will create a batch context, that should be used in subsequent
 // calls to `GetNnsByVector` and `GetNnsByItem`.
 func (idx *AnnoyIndexImpl[TV, TIX]) CreateContext() interfaces.AnnoyIndexContext[TV, TIX] {
-	nnsLen := idx.batchMaxNNS
-	if nnsLen < 1 {
-		nnsLen = int(idx._n_nodes) * 2
-	}
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+
+	return idx.createContext()
+}
+
+// createContext is `CreateContext` without locking.
+func (idx *AnnoyIndexImpl[TV, TIX]) createContext() *BatchContext[TV, TIX] {
+	nnsLen := idx.contextLength()
 
 	bc := &BatchContext[TV, TIX]{
-		length:   nnsLen,
-		nns:      make([]TIX, nnsLen),
-		nns_dist: make([]*interfaces.Pair[TV, TIX], nnsLen),
+		length:    nnsLen,
+		nns:       make([]TIX, nnsLen),
+		nns_dist:  make([]*interfaces.Pair[TV, TIX], nnsLen),
+		queryNode: make([]byte, idx.nodeSize), // Allocate mem on gcheap
 	}
 
 	for i := 0; i < nnsLen; i++ {
@@ -39,16 +49,65 @@ func (idx *AnnoyIndexImpl[TV, TIX]) CreateContext() interfaces.AnnoyIndexContext
 	return bc
 }
 
+// ensureLength grows the context so that it has room for _n_ candidates. This is needed
+// when the context was created before a larger index was built or loaded.
+func (bc *BatchContext[TV, TIX]) ensureLength(n int) {
+	if bc.length >= n {
+		return
+	}
+
+	if len(bc.nns) < n {
+		bc.nns = append(bc.nns, make([]TIX, n-len(bc.nns))...)
+	}
+
+	bc.ensurePairs(n)
+	bc.length = n
+}
+
+// addCandidates writes the _items_ as candidates after the first _cnt_ candidates and returns
+// the new count. An item may be collected once per tree, hence the candidates may outgrow
+// the context length and the buffer is then grown.
+func (bc *BatchContext[TV, TIX]) addCandidates(cnt int, items ...TIX) int {
+	bc.nns = append(bc.nns[:cnt], items...)
+	bc.nns = bc.nns[:cap(bc.nns)]
+
+	return cnt + len(items)
+}
+
+// ensurePairs grows the distance pairs so that at least _n_ pairs are available.
+func (bc *BatchContext[TV, TIX]) ensurePairs(n int) {
+	for len(bc.nns_dist) < n {
+		bc.nns_dist = append(bc.nns_dist, &interfaces.Pair[TV, TIX]{})
+	}
+}
+
+// contextLength returns the number of candidates a `BatchContext` must have room for.
+func (idx *AnnoyIndexImpl[TV, TIX]) contextLength() int {
+	if idx.batchMaxNNS < 1 {
+		return int(idx._n_nodes) * 2
+	}
+
+	return idx.batchMaxNNS
+}
+
 // GetDistance returns the distance between the two indexes.
 func (idx *AnnoyIndexImpl[TV, TIX]) GetDistance(i, j TIX) TV {
-	ni := idx.distance.MapNodeToMemory(idx._nodes, i)
-	nj := idx.distance.MapNodeToMemory(idx._nodes, j)
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
 
 	return idx.distance.NormalizedDistance(
-		idx.distance.Distance(ni, nj),
+		idx.distance.Distance(idx.itemNode(i), idx.itemNode(j)),
 	)
 }
 
+// GetRawDistance returns the raw distance, i.e. not normalized, between the two indexes.
+func (idx *AnnoyIndexImpl[TV, TIX]) GetRawDistance(i, j TIX) TV {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+
+	return idx.distance.Distance(idx.itemNode(i), idx.itemNode(j))
+}
+
 // GetNnsByItem will search for the closest vectors to the given _item_ in the index.
 // When _numNodesToInspect_ is -1, it will search number of trees in index * _numReturn_.
 func (idx *AnnoyIndexImpl[TV, TIX]) GetNnsByItem(
@@ -56,17 +115,27 @@ func (idx *AnnoyIndexImpl[TV, TIX]) GetNnsByItem(
 	numReturn, numNodesToInspect int,
 	ctx interfaces.AnnoyIndexContext[TV, TIX],
 ) (result []TIX, distances []TV) {
-
-	node := idx.distance.MapNodeToMemory(
-		idx._nodes,
-		item,
+	return idx.GetNnsByItemWithOptions(
+		item, numReturn, numNodesToInspect, ctx, interfaces.SearchOptions[TIX]{},
 	)
+}
 
-	return idx.GetNnsByVector(
-		node.GetVector(idx.vectorLength),
+// GetNnsByItemWithOptions is same as `GetNnsByItem` but with search _opts_.
+func (idx *AnnoyIndexImpl[TV, TIX]) GetNnsByItemWithOptions(
+	item TIX,
+	numReturn, numNodesToInspect int,
+	ctx interfaces.AnnoyIndexContext[TV, TIX],
+	opts interfaces.SearchOptions[TIX],
+) (result []TIX, distances []TV) {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+
+	return idx.search(
+		idx.getItem(item),
 		numReturn,
 		numNodesToInspect,
-		ctx,
+		ctx.(*BatchContext[TV, TIX]),
+		opts,
 	)
 }
 
@@ -77,109 +146,253 @@ func (idx *AnnoyIndexImpl[TV, TIX]) GetNnsByVector(
 	numReturn, numNodesToInspect int,
 	ctx interfaces.AnnoyIndexContext[TV, TIX],
 ) (result []TIX, distances []TV) {
-	bc := ctx.(*BatchContext[TV, TIX])
-	q := sort.NewPriorityQueue[TV, TIX]()
+	return idx.GetNnsByVectorWithOptions(
+		vector, numReturn, numNodesToInspect, ctx, interfaces.SearchOptions[TIX]{},
+	)
+}
+
+// GetNnsByVectorWithOptions is same as `GetNnsByVector` but with search _opts_.
+func (idx *AnnoyIndexImpl[TV, TIX]) GetNnsByVectorWithOptions(
+	vector []TV,
+	numReturn, numNodesToInspect int,
+	ctx interfaces.AnnoyIndexContext[TV, TIX],
+	opts interfaces.SearchOptions[TIX],
+) (result []TIX, distances []TV) {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
 
-	if numNodesToInspect == -1 {
-		numNodesToInspect = numReturn * len(idx._roots)
+	return idx.search(vector, numReturn, numNodesToInspect, ctx.(*BatchContext[TV, TIX]), opts)
+}
+
+// GetNnsByVectorInto is same as `GetNnsByVector` but writes the closest items into _result_
+// and their distances into _distances_ instead of allocating new slices. At most `len(result)`
+// items are searched for and the number of items written is returned. The _distances_ may be
+// `nil`, if shorter than _result_, at most `len(distances)` items are searched for.
+//
+// It does not allocate any memory once the _ctx_ has been used for a search, hence use it in
+// hot loops.
+func (idx *AnnoyIndexImpl[TV, TIX]) GetNnsByVectorInto(
+	vector []TV,
+	numNodesToInspect int,
+	result []TIX,
+	distances []TV,
+	ctx interfaces.AnnoyIndexContext[TV, TIX],
+) int {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+
+	numReturn := len(result)
+
+	if distances != nil && len(distances) < numReturn {
+		numReturn = len(distances)
 	}
 
-	for i := range idx._roots {
-		q.Push(idx.distance.PQInitialValue(), idx._roots[i])
+	nns_dist := idx.searchCandidates(
+		vector, numReturn, numNodesToInspect, ctx.(*BatchContext[TV, TIX]),
+		interfaces.SearchOptions[TIX]{},
+	)
+
+	for i, pair := range nns_dist {
+		result[i] = pair.Second
+
+		if distances != nil {
+			distances[i] = idx.distance.NormalizedDistance(pair.First)
+		}
 	}
 
-	cnt := 0
+	return len(nns_dist)
+}
 
-	for cnt < numNodesToInspect && !q.Empty() {
-		top := q.Top()
+// search is the actual search for the closest items to the _vector_.
+func (idx *AnnoyIndexImpl[TV, TIX]) search(
+	vector []TV,
+	numReturn, numNodesToInspect int,
+	bc *BatchContext[TV, TIX],
+	opts interfaces.SearchOptions[TIX],
+) (result []TIX, distances []TV) {
+	nns_dist := idx.searchCandidates(vector, numReturn, numNodesToInspect, bc, opts)
 
-		d := top.First
-		i := top.Second
-		nd := idx.distance.MapNodeToMemory(idx._nodes, i)
+	if len(nns_dist) == 0 {
+		return
+	}
 
-		q.Pop()
+	result = make([]TIX, len(nns_dist))
 
-		nDescendants := nd.GetNumberOfDescendants()
+	for i, pair := range nns_dist {
+		result[i] = pair.Second
+	}
 
-		if nDescendants == 1 && i < idx._n_items {
-			bc.nns[cnt] = i
-			cnt++
-		} else if nDescendants <= idx.maxDescendants {
-			dst := nd.GetChildren()
-			if len(dst) == int(nDescendants) {
-				copy(bc.nns[TIX(cnt):], dst)
-			} else {
-				copy(bc.nns[TIX(cnt):], dst[:nDescendants])
-			}
-			cnt += int(nDescendants)
+	if opts.OmitDistances {
+		return
+	}
+
+	distances = make([]TV, len(nns_dist))
+
+	for i, pair := range nns_dist {
+		if opts.RawDistances {
+			distances[i] = pair.First
 		} else {
-			// Node is normal of the split plane.
-			margin := idx.distance.Margin(nd, vector)
-			children := nd.GetChildren()
-
-			q.Push(
-				idx.distance.PQDistance(d, margin, interfaces.SideRight),
-				children[interfaces.SideRight],
-			)
-
-			q.Push(
-				idx.distance.PQDistance(d, margin, interfaces.SideLeft),
-				children[interfaces.SideLeft],
-			)
+			distances[i] = idx.distance.NormalizedDistance(pair.First)
 		}
 	}
 
-	// Get distances for all items
-	// To avoid calculating distance multiple times for any items, sort by id
-	nns := bc.nns[:cnt]
-	idx.sorter.SortSlice(nns)
+	return
+}
 
-	mem := make([]byte, idx.nodeSize) // Allocate mem on gcheap
+// queryNode prepares the node, in the _bc_ memory, holding the _vector_ to search for. If
+// _normalized_, the vector is known to be of unit length and the norm is not calculated.
+func (idx *AnnoyIndexImpl[TV, TIX]) queryNode(
+	vector []TV,
+	normalized bool,
+	bc *BatchContext[TV, TIX],
+) interfaces.Node[TV, TIX] {
+	clear(bc.queryNode)
 
-	// Prepare node to search for
 	v_node := idx.distance.MapNodeToMemory(
-		unsafe.Pointer(unsafe.SliceData(mem)),
+		unsafe.Pointer(unsafe.SliceData(bc.queryNode)),
 		0,
 	)
 
 	v_node.SetVector(vector)
 
-	idx.distance.InitNode(v_node)
+	if normalized {
+		v_node.SetNorm(1)
+	} else {
+		idx.distance.InitNode(v_node)
+	}
 
-	var (
-		lastset bool
-		last    TIX
-	)
+	return v_node
+}
 
-	cnt = 0
+// searchCandidates searches for the closest items to the _vector_ and returns at most
+// _numReturn_ sorted pairs of raw distance and item. The pairs are owned by the _bc_.
+func (idx *AnnoyIndexImpl[TV, TIX]) searchCandidates(
+	vector []TV,
+	numReturn, numNodesToInspect int,
+	bc *BatchContext[TV, TIX],
+	opts interfaces.SearchOptions[TIX],
+) []*interfaces.Pair[TV, TIX] {
+	if idx._nodes == nil {
+		// Closed
+		return bc.nns_dist[:0]
+	}
+
+	bc.ensureLength(idx.contextLength())
+
+	q := &bc.pq
+	q.Reset()
+
+	filter := idx.searchFilter(opts.Filter)
+
+	if numNodesToInspect == -1 {
+		numNodesToInspect = numReturn * len(idx._roots)
+	}
+
+	// The budget is doubled when too few items passes the filter, hence it must be positive
+	if numNodesToInspect < 1 {
+		numNodesToInspect = 1
+	}
+
+	// maxInspect is the number of items inspected when all trees are fully traversed
+	maxInspect := int(idx._n_items) * len(idx._roots)
+
+	for i := range idx._roots {
+		q.Push(idx.distance.PQInitialValue(), idx._roots[i])
+	}
+
+	// inspected is the number of candidate items inspected, cnt is the number of
+	// candidates that passed the filter
+	inspected, cnt := 0, 0
+
+	for {
+		before := inspected
+
+		for inspected < numNodesToInspect && !q.Empty() {
+			top := q.Pop()
+
+			d := top.First
+			i := top.Second
+			nd := idx.distance.MapNodeToMemory(idx._nodes, i)
 
-	for i := 0; i < len(nns); i++ {
-		j := nns[i]
-		if lastset && j == last {
-			continue
+			nDescendants := nd.GetNumberOfDescendants()
+
+			if nDescendants == 1 && i < idx._n_items {
+				if filter == nil || filter(i) {
+					cnt = bc.addCandidates(cnt, i)
+				}
+
+				inspected++
+			} else if nDescendants <= idx.maxDescendants {
+				dst := nd.GetChildren()[:nDescendants]
+
+				if filter == nil {
+					cnt = bc.addCandidates(cnt, dst...)
+				} else {
+					for _, j := range dst {
+						if filter(j) {
+							cnt = bc.addCandidates(cnt, j)
+						}
+					}
+				}
+
+				inspected += int(nDescendants)
+			} else {
+				// Node is normal of the split plane.
+				margin := idx.distance.Margin(nd, vector)
+				children := splitChildren(nd)
+
+				q.Push(
+					idx.distance.PQDistance(d, margin, interfaces.SideRight),
+					children[interfaces.SideRight],
+				)
+
+				q.Push(
+					idx.distance.PQDistance(d, margin, interfaces.SideLeft),
+					children[interfaces.SideLeft],
+				)
+			}
 		}
 
-		last = j
-		lastset = true
-		n := idx.distance.MapNodeToMemory(idx._nodes, j)
+		// To avoid calculating distance multiple times for any items, sort by id
+		// and remove duplicates
+		cnt = dedupe(bc.nns[:cnt], idx.sorter)
+
+		if filter == nil || cnt >= numReturn || q.Empty() {
+			break
+		}
+
+		if inspected == before || numNodesToInspect >= maxInspect {
+			// No new candidates or all trees have been inspected
+			break
+		}
+
+		// Too few items passed the filter, inspect more nodes. A leaf may have overshot the
+		// budget, hence double what has been inspected so far.
+		numNodesToInspect = 2 * max(numNodesToInspect, inspected)
+	}
+
+	nns := bc.nns[:cnt]
 
-		if n.GetNumberOfDescendants() == 1 { // This is only to guard a really obscure case, #284
-			jn := idx.distance.MapNodeToMemory(idx._nodes, j)
+	v_node := idx.queryNode(vector, opts.QueryNormalized, bc)
 
+	cnt = 0
+
+	for _, j := range nns {
+		n := idx.distance.MapNodeToMemory(idx._nodes, j)
+
+		// The descendants check is only to guard a really obscure case, #284
+		if n.GetNumberOfDescendants() == 1 && !idx.isShadowed(j) {
 			pair := bc.nns_dist[cnt]
-			pair.First = idx.distance.Distance(v_node, jn)
+			pair.First = idx.distance.Distance(v_node, n)
 			pair.Second = j
 
 			cnt++
 		}
 	}
 
-	var nns_dist []*interfaces.Pair[TV, TIX]
-	if cnt < len(nns) {
-		nns_dist = bc.nns_dist[:cnt]
-	} else {
-		nns_dist = bc.nns_dist[:len(nns)]
-	}
+	cnt = idx.deltaCandidates(v_node, bc, cnt, filter)
+
+	nns_dist := bc.nns_dist[:cnt]
 
 	var middle int
 	if numReturn < cnt {
@@ -190,10 +403,29 @@ func (idx *AnnoyIndexImpl[TV, TIX]) GetNnsByVector(
 
 	idx.sorter.PartialSortSlice(nns_dist, 0, middle, len(nns_dist))
 
-	for i := 0; i < middle; i++ {
-		distances = append(distances, idx.distance.NormalizedDistance(nns_dist[i].First))
-		result = append(result, nns_dist[i].Second)
+	return nns_dist[:middle]
+}
+
+// dedupe sorts the _nns_ and moves all unique items to the front. It returns the number
+// of unique items.
+func dedupe[TV interfaces.VectorType, TIX interfaces.IndexTypes](
+	nns []TIX,
+	sorter interfaces.Sorter[TV, TIX],
+) int {
+	if len(nns) == 0 {
+		return 0
 	}
 
-	return
+	sorter.SortSlice(nns)
+
+	n := 1
+
+	for i := 1; i < len(nns); i++ {
+		if nns[i] != nns[n-1] {
+			nns[n] = nns[i]
+			n++
+		}
+	}
+
+	return n
 }
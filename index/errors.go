@@ -0,0 +1,28 @@
+package index
+
+import "errors"
+
+var (
+	// ErrIndexLoaded is returned when trying to modify an index that has been loaded from file.
+	ErrIndexLoaded = errors.New("index is loaded")
+	// ErrAlreadyBuilt is returned when trying to add items to, or build, an already built index.
+	ErrAlreadyBuilt = errors.New("index already built")
+	// ErrNotBuilt is returned when an operation requires a built index.
+	ErrNotBuilt = errors.New("index not built")
+	// ErrNotCompacted is returned when saving an index that has items in the delta segment.
+	ErrNotCompacted = errors.New("index has items not compacted into the trees")
+	// ErrNothingToCompact is returned when compacting an index that is not incremental and
+	// has no deleted items.
+	ErrNothingToCompact = errors.New("nothing to compact")
+	// ErrDimensionMismatch is returned when a vector length do not match the index vector length.
+	ErrDimensionMismatch = errors.New("vector length mismatch")
+	// ErrInvalidHeader is returned when the index file header is corrupt or of an
+	// unsupported version.
+	ErrInvalidHeader = errors.New("invalid index file header")
+	// ErrIncompatibleIndex is returned when the index file header describes an index
+	// that do not match the configuration of the index it is loaded into.
+	ErrIncompatibleIndex = errors.New("index file is incompatible with index")
+	// ErrNotAnnoyCompatible is returned when building, saving or loading an index configured
+	// with `WithAnnoyCompatibility` that can't be compatible with Spotify Annoy.
+	ErrNotAnnoyCompatible = errors.New("index is not annoy compatible")
+)
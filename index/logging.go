@@ -0,0 +1,64 @@
+package index
+
+import (
+	"context"
+	"log/slog"
+	"os"
+
+	"github.com/mariotoffia/goannoy/interfaces"
+	"github.com/mariotoffia/goannoy/utils"
+)
+
+// LevelTrace is the log level used when dumping individual nodes. It is below
+// `slog.LevelDebug` since it produces a vast amount of output.
+const LevelTrace = slog.LevelDebug - 4
+
+// WithLogger sets the _logger_ that receives the build statistics, save and load
+// events (`slog.LevelInfo` and `slog.LevelDebug`) and node dumps (`LevelTrace`).
+//
+// If not set, nothing is logged unless _logVerbose_ is passed to `New`, in which case
+// everything is logged as text to stdout.
+func WithLogger[TV interfaces.VectorType, TIX interfaces.IndexTypes](
+	logger *slog.Logger,
+) Option[TV, TIX] {
+	return func(idx *AnnoyIndexImpl[TV, TIX]) {
+		idx.logger = logger
+	}
+}
+
+// defaultLogger returns the logger to use when no logger has been set using `WithLogger`.
+func defaultLogger(logVerbose bool) *slog.Logger {
+	if logVerbose {
+		return slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: LevelTrace}),
+		)
+	}
+
+	return slog.New(discardHandler{})
+}
+
+// traceEnabled returns `true` if the logger accepts `LevelTrace` records.
+func (idx *AnnoyIndexImpl[TV, TIX]) traceEnabled() bool {
+	return idx.logger.Enabled(context.Background(), LevelTrace)
+}
+
+// traceNode logs the _node_ at `LevelTrace`. The node is only dumped when the level
+// is enabled since it is expensive.
+func (idx *AnnoyIndexImpl[TV, TIX]) traceNode(msg string, item TIX, node interfaces.Node[TV, TIX]) {
+	if !idx.traceEnabled() {
+		return
+	}
+
+	idx.logger.Log(
+		context.Background(), LevelTrace, msg,
+		"item", item, "node", utils.DumpNode(idx.distance, node),
+	)
+}
+
+// discardHandler is a `slog.Handler` that discards all records.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
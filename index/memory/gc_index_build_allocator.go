@@ -23,10 +23,10 @@ func (a *GoGCIndexBuildAllocatorImpl) Free() {
 	a.memory = nil
 }
 
-func (a *GoGCIndexBuildAllocatorImpl) Reallocate(byteSize int) unsafe.Pointer {
+func (a *GoGCIndexBuildAllocatorImpl) Reallocate(byteSize int) (unsafe.Pointer, error) {
 	if a.memory != nil && byteSize < a.ptrSize {
 		// No new memory needed
-		return a.ptr
+		return a.ptr, nil
 	}
 
 	data := make([]byte, byteSize)
@@ -45,5 +45,5 @@ func (a *GoGCIndexBuildAllocatorImpl) Reallocate(byteSize int) unsafe.Pointer {
 	a.ptrSize = byteSize
 	a.memory = data
 
-	return ptr
+	return ptr, nil
 }
@@ -0,0 +1,83 @@
+package memory
+
+import (
+	"fmt"
+	"io"
+	"unsafe"
+
+	"github.com/mariotoffia/goannoy/interfaces"
+)
+
+// nodeAlignment is the alignment required by the nodes, i.e. the largest field type.
+const nodeAlignment = 8
+
+type bytesIndexAllocation struct {
+	ptr  unsafe.Pointer
+	data []byte
+}
+
+func (bi *bytesIndexAllocation) Ptr() unsafe.Pointer {
+	return bi.ptr
+}
+
+func (bi *bytesIndexAllocation) Size() int64 {
+	return int64(len(bi.data))
+}
+
+// Implements `io.Closer` interface
+func (bi *bytesIndexAllocation) Close() error {
+	bi.data = nil
+	bi.ptr = nil
+
+	return nil
+}
+
+// BytesIndex uses _data_, e.g. embedded using `//go:embed`, as index memory. When the _data_
+// is suitably aligned for the nodes it is used in place and hence must not be modified while
+// in use, otherwise it is copied.
+func BytesIndex(data []byte) (interfaces.AllocatedIndex, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("index data is empty")
+	}
+
+	if uintptr(unsafe.Pointer(unsafe.SliceData(data)))%nodeAlignment != 0 {
+		aligned := alignedBytes(len(data))
+		copy(aligned, data)
+
+		data = aligned
+	}
+
+	return &bytesIndexAllocation{
+		ptr:  unsafe.Pointer(unsafe.SliceData(data)),
+		data: data,
+	}, nil
+}
+
+// ReaderAtIndex reads _size_ bytes from _r_ into memory to be used as index memory.
+func ReaderAtIndex(r io.ReaderAt, size int64) (interfaces.AllocatedIndex, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("index data is empty")
+	}
+
+	data := alignedBytes(int(size))
+
+	if n, err := r.ReadAt(data, 0); n < len(data) {
+		if err == nil || err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+
+		return nil, err
+	}
+
+	return &bytesIndexAllocation{
+		ptr:  unsafe.Pointer(unsafe.SliceData(data)),
+		data: data,
+	}, nil
+}
+
+// alignedBytes allocates _size_ bytes aligned for the nodes.
+func alignedBytes(size int) []byte {
+	words := make([]uint64, (size+nodeAlignment-1)/nodeAlignment)
+
+	return unsafe.Slice((*byte)(unsafe.Pointer(unsafe.SliceData(words))), size)
+}
@@ -4,12 +4,15 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sync"
 	"unsafe"
 
 	"github.com/mariotoffia/goannoy/interfaces"
 )
 
 type fileIndexerAllocator struct {
+	// lock protects the _indexes_ since indexes may be opened and closed concurrently.
+	lock    sync.Mutex
 	indexes map[string]*fileIndexAllocation
 }
 
@@ -31,7 +34,14 @@ func (fi *fileIndexAllocation) Size() int64 {
 
 // Implements `io.Closer` interface
 func (fi *fileIndexAllocation) Close() error {
-	delete(fi.parent.indexes, fi.fqFile)
+	fi.parent.lock.Lock()
+
+	// The file may have been opened again since
+	if fi.parent.indexes[fi.fqFile] == fi {
+		delete(fi.parent.indexes, fi.fqFile)
+	}
+
+	fi.parent.lock.Unlock()
 
 	fi.data = nil
 	fi.ptr = nil
@@ -46,6 +56,9 @@ func FileIndexMemoryAllocator() *fileIndexerAllocator {
 }
 
 func (mm *fileIndexerAllocator) Get(fqFile string) (interfaces.AllocatedIndex, bool) {
+	mm.lock.Lock()
+	defer mm.lock.Unlock()
+
 	index, ok := mm.indexes[fqFile]
 	return index, ok
 }
@@ -89,6 +102,9 @@ func (mm *fileIndexerAllocator) Open(fqFile string) (interfaces.AllocatedIndex,
 		data:   data,
 	}
 
+	mm.lock.Lock()
 	mm.indexes[fqFile] = fi
+	mm.lock.Unlock()
+
 	return fi, nil
 }
@@ -2,6 +2,7 @@ package memory
 
 import (
 	"os"
+	"sync"
 	"syscall"
 	"unsafe"
 
@@ -9,6 +10,8 @@ import (
 )
 
 type mmapIndexAllocator struct {
+	// lock protects the _indexes_ since indexes may be opened and closed concurrently.
+	lock    sync.Mutex
 	indexes map[string]*mmapIndexAllocation
 }
 
@@ -31,7 +34,14 @@ func (mi *mmapIndexAllocation) Size() int64 {
 
 // Implements `io.Closer` interface
 func (mi *mmapIndexAllocation) Close() error {
-	delete(mi.parent.indexes, mi.fqFile)
+	mi.parent.lock.Lock()
+
+	// The file may have been opened again since
+	if mi.parent.indexes[mi.fqFile] == mi {
+		delete(mi.parent.indexes, mi.fqFile)
+	}
+
+	mi.parent.lock.Unlock()
 
 	var err error
 
@@ -59,6 +69,9 @@ func MmapIndexAllocator() *mmapIndexAllocator {
 }
 
 func (mm *mmapIndexAllocator) Get(fqFile string) (interfaces.AllocatedIndex, bool) {
+	mm.lock.Lock()
+	defer mm.lock.Unlock()
+
 	index, ok := mm.indexes[fqFile]
 	return index, ok
 }
@@ -99,6 +112,9 @@ func (mm *mmapIndexAllocator) Open(fqFile string) (interfaces.AllocatedIndex, er
 		data:   data,
 	}
 
+	mm.lock.Lock()
 	mm.indexes[fqFile] = mi
+	mm.lock.Unlock()
+
 	return mi, nil
 }
@@ -0,0 +1,126 @@
+package memory
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// MmapIndexBuildAllocatorImpl is a `interfaces.BuildIndexAllocator` that builds the index
+// directly into a memory mapped file (on disk build). This makes it possible to build
+// indexes that are larger than the available memory.
+//
+// Each time more memory is needed, the file is grown using ftruncate and re-mapped.
+type MmapIndexBuildAllocatorImpl struct {
+	fqFile string
+	file   *os.File
+	ptr    unsafe.Pointer
+	data   []byte
+}
+
+// MmapIndexBuildAllocator creates a new on disk build allocator that will build
+// into _fqFile_. The file is created (or truncated) upon first allocation.
+func MmapIndexBuildAllocator(fqFile string) *MmapIndexBuildAllocatorImpl {
+	return &MmapIndexBuildAllocatorImpl{
+		fqFile: fqFile,
+	}
+}
+
+// FileName returns the file that the index is built into.
+func (a *MmapIndexBuildAllocatorImpl) FileName() string {
+	return a.fqFile
+}
+
+// Free will unmap and close the file. The file itself is kept on disk.
+func (a *MmapIndexBuildAllocatorImpl) Free() {
+	if a.data != nil {
+		syscall.Munmap(a.data)
+	}
+
+	if a.file != nil {
+		a.file.Close()
+	}
+
+	a.ptr = nil
+	a.data = nil
+	a.file = nil
+}
+
+// Reallocate grows the file to _byteSize_ and maps it again. Errors from creating,
+// growing or mapping the file are returned.
+func (a *MmapIndexBuildAllocatorImpl) Reallocate(byteSize int) (unsafe.Pointer, error) {
+	if a.data != nil && byteSize < len(a.data) {
+		// No new memory needed
+		return a.ptr, nil
+	}
+
+	if a.file == nil {
+		file, err := os.OpenFile(a.fqFile, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create on disk build file: %w", err)
+		}
+
+		a.file = file
+	}
+
+	ptr, err := a.remap(byteSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to grow on disk build file %s: %w", a.fqFile, err)
+	}
+
+	return ptr, nil
+}
+
+// Truncate shrinks the file to exactly _byteSize_ and flushes it to disk.
+func (a *MmapIndexBuildAllocatorImpl) Truncate(byteSize int) (unsafe.Pointer, error) {
+	if a.file == nil {
+		return nil, fmt.Errorf("on disk build file %s is not allocated", a.fqFile)
+	}
+
+	ptr, err := a.remap(byteSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return ptr, a.file.Sync()
+}
+
+// remap will resize the file to _byteSize_ and map it again. Since the old
+// mapping is shared, all writes are already in the file.
+func (a *MmapIndexBuildAllocatorImpl) remap(byteSize int) (unsafe.Pointer, error) {
+	// Resize before unmapping, so the old mapping is still valid if it fails
+	if err := a.file.Truncate(int64(byteSize)); err != nil {
+		return nil, err
+	}
+
+	if a.data != nil {
+		if err := syscall.Munmap(a.data); err != nil {
+			return nil, err
+		}
+
+		a.data = nil
+		a.ptr = nil
+	}
+
+	if byteSize == 0 {
+		return nil, nil
+	}
+
+	data, err := syscall.Mmap(
+		int(a.file.Fd()),
+		0,
+		byteSize,
+		syscall.PROT_READ|syscall.PROT_WRITE,
+		syscall.MAP_SHARED,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	a.data = data
+	a.ptr = unsafe.Pointer(&data[0])
+
+	return a.ptr, nil
+}
@@ -0,0 +1,70 @@
+package index
+
+import (
+	"fmt"
+
+	"github.com/mariotoffia/goannoy/interfaces"
+)
+
+// Option configures optional behaviour of the `AnnoyIndexImpl` when passed to `New`.
+type Option[TV interfaces.VectorType, TIX interfaces.IndexTypes] func(idx *AnnoyIndexImpl[TV, TIX])
+
+// WithFileHeader makes `Save` write a self describing header in front of the nodes. The
+// header records the metric, vector length, element and index widths, item and root
+// counts and a checksum that are validated by `Load`.
+//
+// NOTE: `Load` will always validate a header if present in the file, regardless of this option.
+func WithFileHeader[TV interfaces.VectorType, TIX interfaces.IndexTypes]() Option[TV, TIX] {
+	return func(idx *AnnoyIndexImpl[TV, TIX]) {
+		idx.fileHeader = true
+	}
+}
+
+// WithAnnoyCompatibility guarantees that the files written by `Save` are byte-for-byte
+// compatible with the Spotify Annoy C++/Python library and that files written by
+// Annoy can be loaded.
+//
+// Annoy only supports `float32` vectors (`uint64` for hamming) and `int32` item indexes,
+// hence _TV_ and _TIX_ must be `float32` (or `uint64`) and `uint32`. No file header is
+// written since Annoy would interpret it as nodes and hence, files having a header are
+// rejected by `Load`.
+//
+// If the index can't be compatible, `Build`, `Save` and `Load` returns `ErrNotAnnoyCompatible`.
+func WithAnnoyCompatibility[TV interfaces.VectorType, TIX interfaces.IndexTypes]() Option[TV, TIX] {
+	return func(idx *AnnoyIndexImpl[TV, TIX]) {
+		idx.annoyCompatible = true
+	}
+}
+
+// checkAnnoyCompatibility returns `ErrNotAnnoyCompatible` if the index is configured with
+// `WithAnnoyCompatibility` but the types or options can't be compatible.
+func (idx *AnnoyIndexImpl[TV, TIX]) checkAnnoyCompatibility() error {
+	if !idx.annoyCompatible {
+		return nil
+	}
+
+	if _, ok := any(TIX(0)).(uint32); !ok {
+		return fmt.Errorf("%w: requires uint32 as index type", ErrNotAnnoyCompatible)
+	}
+
+	switch any(TV(0)).(type) {
+	case float32:
+		if idx.distance.Name() == "hamming" {
+			return fmt.Errorf(
+				"%w: requires uint64 as vector type for hamming distance", ErrNotAnnoyCompatible,
+			)
+		}
+	case uint64:
+		if idx.distance.Name() != "hamming" {
+			return fmt.Errorf("%w: requires float32 as vector type", ErrNotAnnoyCompatible)
+		}
+	default:
+		return fmt.Errorf("%w: requires float32 as vector type", ErrNotAnnoyCompatible)
+	}
+
+	if idx.fileHeader {
+		return fmt.Errorf("%w: can't be combined with a file header", ErrNotAnnoyCompatible)
+	}
+
+	return nil
+}
@@ -1,6 +1,7 @@
 package policy
 
 import (
+	"context"
 	"math"
 	"runtime"
 	"sync"
@@ -20,10 +21,16 @@ type annoyIndexMultiThreadedBuildPolicy struct {
 }
 
 func (p *annoyIndexMultiThreadedBuildPolicy) Build(
+	ctx context.Context,
 	builder interfaces.AnnoyIndexBuilder,
 	numberOfTrees, numberOfWorkers int,
 ) {
-	if numberOfWorkers == -1 {
+	switch {
+	case numberOfWorkers == 0:
+		// A single worker, run on the current goroutine
+		builder.ThreadBuild(ctx, numberOfTrees, 0, p)
+		return
+	case numberOfWorkers < 0:
 		numberOfWorkers = int(utils.Max(uint32(1), uint32(runtime.NumCPU())))
 	}
 
@@ -40,7 +47,7 @@ func (p *annoyIndexMultiThreadedBuildPolicy) Build(
 
 			defer wg.Done()
 
-			builder.ThreadBuild(treesPerWorker, workerIdx, p)
+			builder.ThreadBuild(ctx, treesPerWorker, workerIdx, p)
 
 		}(workerIdx, numTreesPerWorker)
 	}
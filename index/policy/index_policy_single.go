@@ -1,6 +1,10 @@
 package policy
 
-import "github.com/mariotoffia/goannoy/interfaces"
+import (
+	"context"
+
+	"github.com/mariotoffia/goannoy/interfaces"
+)
 
 func SingleWorker() *annoyIndexSingleThreadedBuildPolicy {
 	return &annoyIndexSingleThreadedBuildPolicy{}
@@ -9,10 +13,11 @@ func SingleWorker() *annoyIndexSingleThreadedBuildPolicy {
 type annoyIndexSingleThreadedBuildPolicy struct{}
 
 func (p *annoyIndexSingleThreadedBuildPolicy) Build(
+	ctx context.Context,
 	builder interfaces.AnnoyIndexBuilder,
 	numberOfTrees, nThreads int,
 ) {
-	builder.ThreadBuild(numberOfTrees, 0, p)
+	builder.ThreadBuild(ctx, numberOfTrees, 0, p)
 }
 
 func (p *annoyIndexSingleThreadedBuildPolicy) LockNNodes() {
@@ -0,0 +1,41 @@
+package sharded
+
+import (
+	"sort"
+
+	"github.com/mariotoffia/goannoy/interfaces"
+)
+
+// Partition decides the shard, in the range [0, _numShards_), of the _item_.
+type Partition[TIX interfaces.IndexTypes] func(item TIX, numShards int) int
+
+// HashPartition spreads the items evenly over the shards by hashing the item index.
+func HashPartition[TIX interfaces.IndexTypes]() Partition[TIX] {
+	return func(item TIX, numShards int) int {
+		// splitmix64 finalizer, consecutive items ends up in different shards
+		h := uint64(item)
+		h ^= h >> 30
+		h *= 0xbf58476d1ce4e5b9
+		h ^= h >> 27
+		h *= 0x94d049bb133111eb
+		h ^= h >> 31
+
+		return int(h % uint64(numShards))
+	}
+}
+
+// RangePartition puts the items in shards by item index range. The _bounds_ are the, sorted,
+// exclusive upper bounds of all shards but the last, i.e. shard zero holds items below
+// `bounds[0]` and the last shard holds all items from `bounds[len(bounds)-1]`. Items beyond
+// the number of shards end up in the last shard.
+func RangePartition[TIX interfaces.IndexTypes](bounds ...TIX) Partition[TIX] {
+	return func(item TIX, numShards int) int {
+		shard := sort.Search(len(bounds), func(i int) bool { return item < bounds[i] })
+
+		if shard >= numShards {
+			return numShards - 1
+		}
+
+		return shard
+	}
+}
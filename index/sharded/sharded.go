@@ -0,0 +1,440 @@
+// Package sharded spreads the items of a logical index over several `interfaces.AnnoyIndex`
+// shards, each saved into its own file, and fans out searches to all shards.
+package sharded
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"unsafe"
+
+	"github.com/mariotoffia/goannoy/index"
+	"github.com/mariotoffia/goannoy/interfaces"
+)
+
+// location is where a global item resides.
+type location[TIX interfaces.IndexTypes] struct {
+	shard int
+	local TIX
+}
+
+// Item is a single item to ingest using `ShardedIndex.Ingest`.
+type Item[TV interfaces.VectorType, TIX interfaces.IndexTypes] struct {
+	Index  TIX
+	Vector []TV
+}
+
+// ShardedIndex implements `interfaces.AnnoyIndex` by spreading the items over several
+// shards. Each shard uses dense, local, item indexes that are mapped to the global item
+// indexes used by the caller.
+//
+// Searches are fanned out to all shards in parallel and the results are merged by distance.
+type ShardedIndex[TV interfaces.VectorType, TIX interfaces.IndexTypes] struct {
+	shards    []interfaces.AnnoyIndex[TV, TIX]
+	partition Partition[TIX]
+	distance  interfaces.Distance[TV, TIX]
+	// lock protects the item mapping, i.e. _locations_ and _globals_.
+	lock sync.RWMutex
+	// adding serializes the adds to each shard, so the next local item index is known.
+	adding []sync.Mutex
+	// locations maps the global item index to where it resides.
+	locations map[TIX]location[TIX]
+	// globals is the global item index of each local item, per shard.
+	globals [][]TIX
+}
+
+// Ensure that the sharded index can be used in place of a single index.
+var _ interfaces.AnnoyIndex[float32, uint32] = (*ShardedIndex[float32, uint32])(nil)
+
+// New creates a sharded index with _numShards_ shards, each created by _factory_ and
+// configured with the same _distance_. The _distance_ is used to calculate the distance
+// between items residing in different shards. The _partition_ decides the shard of each
+// item, see `HashPartition` and `RangePartition`.
+func New[TV interfaces.VectorType, TIX interfaces.IndexTypes](
+	distance interfaces.Distance[TV, TIX],
+	numShards int,
+	factory func(shard int) interfaces.AnnoyIndex[TV, TIX],
+	partition Partition[TIX],
+) *ShardedIndex[TV, TIX] {
+	if numShards < 1 {
+		panic("sharded index requires at least one shard")
+	}
+
+	si := &ShardedIndex[TV, TIX]{
+		partition: partition,
+		distance:  distance,
+		adding:    make([]sync.Mutex, numShards),
+		locations: map[TIX]location[TIX]{},
+		globals:   make([][]TIX, numShards),
+	}
+
+	for i := 0; i < numShards; i++ {
+		shard := factory(i)
+
+		if shard.VectorLength() != distance.VectorLength() {
+			panic(fmt.Sprintf(
+				"shard %d vector length %d do not match distance vector length %d",
+				i, shard.VectorLength(), distance.VectorLength(),
+			))
+		}
+
+		si.shards = append(si.shards, shard)
+	}
+
+	return si
+}
+
+// Shards returns the shards of the index.
+func (si *ShardedIndex[TV, TIX]) Shards() []interfaces.AnnoyIndex[TV, TIX] {
+	return si.shards
+}
+
+// Implements `io.Closer` interface
+func (si *ShardedIndex[TV, TIX]) Close() error {
+	var errs []error
+
+	for _, shard := range si.shards {
+		errs = append(errs, shard.Close())
+	}
+
+	si.lock.Lock()
+	defer si.lock.Unlock()
+
+	si.locations = map[TIX]location[TIX]{}
+	si.globals = make([][]TIX, len(si.shards))
+
+	return errors.Join(errs...)
+}
+
+func (si *ShardedIndex[TV, TIX]) VectorLength() TIX {
+	return si.distance.VectorLength()
+}
+
+// GetNumberOfItems returns the number of items in all shards.
+func (si *ShardedIndex[TV, TIX]) GetNumberOfItems() TIX {
+	si.lock.RLock()
+	defer si.lock.RUnlock()
+
+	return TIX(len(si.locations))
+}
+
+// GetNumberOfTrees returns the number of trees in each shard.
+func (si *ShardedIndex[TV, TIX]) GetNumberOfTrees() int {
+	return si.shards[0].GetNumberOfTrees()
+}
+
+func (si *ShardedIndex[TV, TIX]) GetItem(itemIndex TIX) []TV {
+	loc, ok := si.lookup(itemIndex)
+
+	if !ok {
+		return nil
+	}
+
+	return si.shards[loc.shard].GetItem(loc.local)
+}
+
+func (si *ShardedIndex[TV, TIX]) AddItem(itemIndex TIX, v []TV) {
+	if err := si.AddItemE(itemIndex, v); err != nil {
+		panic(err.Error())
+	}
+}
+
+// AddItemE adds the item to the shard selected by the partition.
+func (si *ShardedIndex[TV, TIX]) AddItemE(itemIndex TIX, v []TV) error {
+	return si.add(si.partition(itemIndex, len(si.shards)), itemIndex, v)
+}
+
+// Ingest adds all items received on _items_ until it is closed or the _ctx_ is cancelled.
+// Each shard is fed by its own goroutine, hence the shards are filled in parallel.
+func (si *ShardedIndex[TV, TIX]) Ingest(ctx context.Context, items <-chan Item[TV, TIX]) error {
+	var (
+		wg     sync.WaitGroup
+		lock   sync.Mutex
+		errs   []error
+		queues = make([]chan Item[TV, TIX], len(si.shards))
+	)
+
+	for i := range si.shards {
+		queues[i] = make(chan Item[TV, TIX], 64)
+
+		wg.Add(1)
+
+		go func(shard int) {
+			defer wg.Done()
+
+			for item := range queues[shard] {
+				if err := si.add(shard, item.Index, item.Vector); err != nil {
+					lock.Lock()
+					errs = append(errs, err)
+					lock.Unlock()
+				}
+			}
+		}(i)
+	}
+
+	var err error
+
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+			break loop
+		case item, ok := <-items:
+			if !ok {
+				break loop
+			}
+
+			queues[si.partition(item.Index, len(si.shards))] <- item
+		}
+	}
+
+	for _, q := range queues {
+		close(q)
+	}
+
+	wg.Wait()
+
+	return errors.Join(append(errs, err)...)
+}
+
+// add adds the global _item_ to _shard_. A new item gets the next local item index of the
+// shard and is only recorded once the shard has accepted it.
+func (si *ShardedIndex[TV, TIX]) add(shard int, item TIX, v []TV) error {
+	si.adding[shard].Lock()
+	defer si.adding[shard].Unlock()
+
+	si.lock.RLock()
+	loc, ok := si.locations[item]
+
+	if !ok {
+		loc = location[TIX]{shard: shard, local: TIX(len(si.globals[shard]))}
+	}
+
+	si.lock.RUnlock()
+
+	if err := si.shards[loc.shard].AddItemE(loc.local, v); err != nil {
+		return err
+	}
+
+	if !ok {
+		si.lock.Lock()
+		si.locations[item] = loc
+		si.globals[shard] = append(si.globals[shard], item)
+		si.lock.Unlock()
+	}
+
+	return nil
+}
+
+// lookup returns the location of the global _item_, if added.
+func (si *ShardedIndex[TV, TIX]) lookup(item TIX) (location[TIX], bool) {
+	si.lock.RLock()
+	defer si.lock.RUnlock()
+
+	loc, ok := si.locations[item]
+
+	return loc, ok
+}
+
+// shardGlobals returns the global item index of each local item in _shard_. Since items are
+// only appended, the returned slice may be read without holding the lock.
+func (si *ShardedIndex[TV, TIX]) shardGlobals(shard int) []TIX {
+	si.lock.RLock()
+	defer si.lock.RUnlock()
+
+	return si.globals[shard]
+}
+
+func (si *ShardedIndex[TV, TIX]) Build(numberOfTrees, numWorkers int) {
+	if err := si.BuildE(numberOfTrees, numWorkers); err != nil {
+		panic(err.Error())
+	}
+}
+
+func (si *ShardedIndex[TV, TIX]) BuildE(numberOfTrees, numWorkers int) error {
+	return si.BuildContext(context.Background(), interfaces.BuildOptions{
+		NumberOfTrees: numberOfTrees,
+		NumWorkers:    numWorkers,
+	})
+}
+
+// BuildContext builds all shards in parallel, each with the _opts_. The progress reports the
+// sum of trees and nodes over all shards. If any shard fails, the error of each failed shard
+// is returned.
+func (si *ShardedIndex[TV, TIX]) BuildContext(
+	ctx context.Context,
+	opts interfaces.BuildOptions,
+) error {
+	var (
+		lock     sync.Mutex
+		progress = make([]interfaces.BuildProgress, len(si.shards))
+	)
+
+	numberOfTrees := -1
+	if opts.NumberOfTrees != -1 {
+		numberOfTrees = opts.NumberOfTrees * len(si.shards)
+	}
+
+	return si.forEachShard(func(i int, shard interfaces.AnnoyIndex[TV, TIX]) error {
+		shardOpts := opts
+
+		if opts.Progress != nil {
+			shardOpts.Progress = func(p interfaces.BuildProgress) {
+				lock.Lock()
+				defer lock.Unlock()
+
+				progress[i] = p
+
+				total := interfaces.BuildProgress{NumberOfTrees: numberOfTrees, Elapsed: p.Elapsed}
+
+				for _, sp := range progress {
+					total.TreesBuilt += sp.TreesBuilt
+					total.NodesAllocated += sp.NodesAllocated
+				}
+
+				opts.Progress(total)
+			}
+		}
+
+		return shard.BuildContext(ctx, shardOpts)
+	})
+}
+
+func (si *ShardedIndex[TV, TIX]) Unbuild() error {
+	return si.forEachShard(func(_ int, shard interfaces.AnnoyIndex[TV, TIX]) error {
+		return shard.Unbuild()
+	})
+}
+
+// Compact compacts each shard. Shards with nothing to compact, i.e. not incremental and
+// without deleted items, are skipped.
+func (si *ShardedIndex[TV, TIX]) Compact() error {
+	return si.forEachShard(func(_ int, shard interfaces.AnnoyIndex[TV, TIX]) error {
+		if err := shard.Compact(); !errors.Is(err, index.ErrNothingToCompact) {
+			return err
+		}
+
+		return nil
+	})
+}
+
+func (si *ShardedIndex[TV, TIX]) MarkDeleted(items ...TIX) error {
+	locals := make([][]TIX, len(si.shards))
+
+	for _, item := range items {
+		loc, ok := si.lookup(item)
+
+		if !ok {
+			return fmt.Errorf("item %d do not exist", item)
+		}
+
+		locals[loc.shard] = append(locals[loc.shard], loc.local)
+	}
+
+	for shard, items := range locals {
+		if len(items) == 0 {
+			continue
+		}
+
+		if err := si.shards[shard].MarkDeleted(items...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (si *ShardedIndex[TV, TIX]) IsDeleted(item TIX) bool {
+	loc, ok := si.lookup(item)
+
+	return ok && si.shards[loc.shard].IsDeleted(loc.local)
+}
+
+func (si *ShardedIndex[TV, TIX]) DeletedCount() int {
+	cnt := 0
+
+	for _, shard := range si.shards {
+		cnt += shard.DeletedCount()
+	}
+
+	return cnt
+}
+
+func (si *ShardedIndex[TV, TIX]) GetDistance(i, j TIX) TV {
+	return si.distance.NormalizedDistance(si.GetRawDistance(i, j))
+}
+
+// GetRawDistance returns the raw distance between the items. If both reside in the same
+// shard, the shard calculates it, otherwise it is calculated using the distance. If any of
+// the items do not exist, NaN is returned, or the largest distance for `uint64` vectors.
+func (si *ShardedIndex[TV, TIX]) GetRawDistance(i, j TIX) TV {
+	li, iok := si.lookup(i)
+	lj, jok := si.lookup(j)
+
+	if !iok || !jok {
+		return unknownDistance[TV]()
+	}
+
+	if li.shard == lj.shard {
+		return si.shards[li.shard].GetRawDistance(li.local, lj.local)
+	}
+
+	return si.rawDistance(
+		si.shards[li.shard].GetItem(li.local),
+		si.shards[lj.shard].GetItem(lj.local),
+	)
+}
+
+// rawDistance calculates the raw distance between the vectors _u_ and _v_.
+func (si *ShardedIndex[TV, TIX]) rawDistance(u, v []TV) TV {
+	mem := make([]byte, 2*si.distance.NodeSize())
+
+	nu := si.distance.MapNodeToMemory(unsafe.Pointer(unsafe.SliceData(mem)), 0)
+	nv := si.distance.MapNodeToMemory(unsafe.Pointer(unsafe.SliceData(mem)), 1)
+
+	nu.SetVector(u)
+	nv.SetVector(v)
+	si.distance.InitNode(nu)
+	si.distance.InitNode(nv)
+
+	return si.distance.Distance(nu, nv)
+}
+
+// unknownDistance is the distance to an item that do not exist.
+func unknownDistance[TV interfaces.VectorType]() TV {
+	var d TV
+
+	if _, ok := any(d).(uint64); ok {
+		return TV(uint64(math.MaxUint64))
+	}
+
+	return TV(math.NaN())
+}
+
+// forEachShard invokes _f_ for each shard in parallel and joins the errors.
+func (si *ShardedIndex[TV, TIX]) forEachShard(
+	f func(i int, shard interfaces.AnnoyIndex[TV, TIX]) error,
+) error {
+	var wg sync.WaitGroup
+
+	errs := make([]error, len(si.shards))
+
+	for i, shard := range si.shards {
+		wg.Add(1)
+
+		go func(i int, shard interfaces.AnnoyIndex[TV, TIX]) {
+			defer wg.Done()
+
+			if err := f(i, shard); err != nil {
+				errs[i] = fmt.Errorf("shard %d: %w", i, err)
+			}
+		}(i, shard)
+	}
+
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
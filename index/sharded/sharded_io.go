@@ -0,0 +1,125 @@
+package sharded
+
+import (
+	"bufio"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/mariotoffia/goannoy/interfaces"
+	"github.com/mariotoffia/goannoy/utils"
+)
+
+// manifestSuffix is appended to the file name of the manifest that holds the item mapping.
+const manifestSuffix = ".shards"
+
+// manifest is the gob encoded mapping of the local items to the global items.
+type manifest[TIX interfaces.IndexTypes] struct {
+	Globals [][]TIX
+}
+
+// ShardFileName returns the file name of shard _shard_ when saved as _fileName_.
+func ShardFileName(fileName string, shard int) string {
+	return fmt.Sprintf("%s.%d", fileName, shard)
+}
+
+// Save saves each shard, in parallel, into `<fileName>.<shard>` and the item mapping into
+// `<fileName>.shards`.
+func (si *ShardedIndex[TV, TIX]) Save(fileName string) error {
+	if err := si.forEachShard(func(i int, shard interfaces.AnnoyIndex[TV, TIX]) error {
+		return shard.Save(ShardFileName(fileName, i))
+	}); err != nil {
+		return err
+	}
+
+	si.lock.RLock()
+	defer si.lock.RUnlock()
+
+	return utils.WriteFileAtomic(fileName+manifestSuffix, func(w io.Writer) error {
+		return gob.NewEncoder(w).Encode(manifest[TIX]{Globals: si.globals})
+	})
+}
+
+// SaveTo is not supported since a sharded index spans several files, use `Save` instead.
+func (si *ShardedIndex[TV, TIX]) SaveTo(w io.Writer) error {
+	return fmt.Errorf("%w: a sharded index spans several files, use Save", errors.ErrUnsupported)
+}
+
+// Load loads the shards, in parallel, and the item mapping saved by `Save`. The number of
+// shards saved must be the same as in this index.
+func (si *ShardedIndex[TV, TIX]) Load(fileName string) error {
+	return si.load(
+		fileName,
+		func(name string) (fs.File, error) { return os.Open(name) },
+		func(shard interfaces.AnnoyIndex[TV, TIX], name string) error { return shard.Load(name) },
+	)
+}
+
+// LoadFS loads the shards and the item mapping, saved by `Save` as _name_, from _fsys_.
+func (si *ShardedIndex[TV, TIX]) LoadFS(fsys fs.FS, name string) error {
+	return si.load(
+		name,
+		fsys.Open,
+		func(shard interfaces.AnnoyIndex[TV, TIX], name string) error { return shard.LoadFS(fsys, name) },
+	)
+}
+
+// LoadBytes is not supported since a sharded index spans several files, use `LoadFS` instead.
+func (si *ShardedIndex[TV, TIX]) LoadBytes(data []byte) error {
+	return fmt.Errorf("%w: a sharded index spans several files, use LoadFS", errors.ErrUnsupported)
+}
+
+// LoadReaderAt is not supported since a sharded index spans several files, use `LoadFS` instead.
+func (si *ShardedIndex[TV, TIX]) LoadReaderAt(r io.ReaderAt, size int64) error {
+	return fmt.Errorf("%w: a sharded index spans several files, use LoadFS", errors.ErrUnsupported)
+}
+
+// load reads the manifest of _fileName_ using _open_ and loads each shard using _loadShard_.
+func (si *ShardedIndex[TV, TIX]) load(
+	fileName string,
+	open func(name string) (fs.File, error),
+	loadShard func(shard interfaces.AnnoyIndex[TV, TIX], name string) error,
+) error {
+	file, err := open(fileName + manifestSuffix)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	var m manifest[TIX]
+
+	if err := gob.NewDecoder(bufio.NewReader(file)).Decode(&m); err != nil {
+		return fmt.Errorf("failed to read shard manifest from %s: %w", fileName+manifestSuffix, err)
+	}
+
+	if len(m.Globals) != len(si.shards) {
+		return fmt.Errorf(
+			"%s has %d shards but index has %d shards",
+			fileName+manifestSuffix, len(m.Globals), len(si.shards),
+		)
+	}
+
+	if err := si.forEachShard(func(i int, shard interfaces.AnnoyIndex[TV, TIX]) error {
+		return loadShard(shard, ShardFileName(fileName, i))
+	}); err != nil {
+		return err
+	}
+
+	si.lock.Lock()
+	defer si.lock.Unlock()
+
+	si.globals = m.Globals
+	si.locations = map[TIX]location[TIX]{}
+
+	for shard, globals := range si.globals {
+		for local, item := range globals {
+			si.locations[item] = location[TIX]{shard: shard, local: TIX(local)}
+		}
+	}
+
+	return nil
+}
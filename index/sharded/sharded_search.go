@@ -0,0 +1,285 @@
+package sharded
+
+import (
+	"runtime"
+	"slices"
+	"sync"
+
+	"github.com/mariotoffia/goannoy/interfaces"
+)
+
+// shardedContext holds one context per shard.
+type shardedContext[TV interfaces.VectorType, TIX interfaces.IndexTypes] struct {
+	contexts []interfaces.AnnoyIndexContext[TV, TIX]
+}
+
+// candidate is an item found in one of the shards.
+type candidate[TV interfaces.VectorType, TIX interfaces.IndexTypes] struct {
+	// raw is the raw distance, as calculated by the shard, used to merge the shards.
+	raw TV
+	// distance is the distance returned to the caller.
+	distance TV
+	item     TIX
+}
+
+// CreateContext creates a context holding one context per shard.
+func (si *ShardedIndex[TV, TIX]) CreateContext() interfaces.AnnoyIndexContext[TV, TIX] {
+	sc := &shardedContext[TV, TIX]{}
+
+	for _, shard := range si.shards {
+		sc.contexts = append(sc.contexts, shard.CreateContext())
+	}
+
+	return sc
+}
+
+func (si *ShardedIndex[TV, TIX]) GetNnsByItem(
+	item TIX,
+	numReturn, numNodesToInspect int,
+	ctx interfaces.AnnoyIndexContext[TV, TIX],
+) (result []TIX, distances []TV) {
+	return si.GetNnsByItemWithOptions(
+		item, numReturn, numNodesToInspect, ctx, interfaces.SearchOptions[TIX]{},
+	)
+}
+
+func (si *ShardedIndex[TV, TIX]) GetNnsByItemWithOptions(
+	item TIX,
+	numReturn, numNodesToInspect int,
+	ctx interfaces.AnnoyIndexContext[TV, TIX],
+	opts interfaces.SearchOptions[TIX],
+) (result []TIX, distances []TV) {
+	return si.GetNnsByVectorWithOptions(si.GetItem(item), numReturn, numNodesToInspect, ctx, opts)
+}
+
+func (si *ShardedIndex[TV, TIX]) GetNnsByVector(
+	vector []TV,
+	numReturn, numNodesToInspect int,
+	ctx interfaces.AnnoyIndexContext[TV, TIX],
+) (result []TIX, distances []TV) {
+	return si.GetNnsByVectorWithOptions(
+		vector, numReturn, numNodesToInspect, ctx, interfaces.SearchOptions[TIX]{},
+	)
+}
+
+// GetNnsByVectorWithOptions searches all shards in parallel, each for _numReturn_ items, and
+// merges the results by raw distance. The _opts_ filter is invoked with global item indexes.
+func (si *ShardedIndex[TV, TIX]) GetNnsByVectorWithOptions(
+	vector []TV,
+	numReturn, numNodesToInspect int,
+	ctx interfaces.AnnoyIndexContext[TV, TIX],
+	opts interfaces.SearchOptions[TIX],
+) (result []TIX, distances []TV) {
+	shardOpts := opts
+
+	// Raw distances are needed to merge, e.g. normalized dot product distances are descending
+	shardOpts.OmitDistances = false
+	shardOpts.RawDistances = true
+
+	candidates := si.fanOut(ctx, shardOpts, func(
+		shard interfaces.AnnoyIndex[TV, TIX],
+		ctx interfaces.AnnoyIndexContext[TV, TIX],
+		opts interfaces.SearchOptions[TIX],
+	) ([]TIX, []TV, []TV) {
+		items, raw := shard.GetNnsByVectorWithOptions(vector, numReturn, numNodesToInspect, ctx, opts)
+		return items, raw, raw
+	})
+
+	if len(candidates) > numReturn {
+		candidates = candidates[:numReturn]
+	}
+
+	for _, c := range candidates {
+		result = append(result, c.item)
+
+		switch {
+		case opts.OmitDistances:
+		case opts.RawDistances:
+			distances = append(distances, c.raw)
+		default:
+			distances = append(distances, si.distance.NormalizedDistance(c.raw))
+		}
+	}
+
+	return
+}
+
+// GetNnsByVectorInto is same as `GetNnsByVector` but writes into _result_ and _distances_.
+//
+// NOTE: Unlike `index.AnnoyIndexImpl`, this allocates since the results of the shards are merged.
+func (si *ShardedIndex[TV, TIX]) GetNnsByVectorInto(
+	vector []TV,
+	numNodesToInspect int,
+	result []TIX,
+	distances []TV,
+	ctx interfaces.AnnoyIndexContext[TV, TIX],
+) int {
+	numReturn := len(result)
+
+	if distances != nil && len(distances) < numReturn {
+		numReturn = len(distances)
+	}
+
+	items, dists := si.GetNnsByVector(vector, numReturn, numNodesToInspect, ctx)
+
+	copy(result, items)
+
+	if distances != nil {
+		copy(distances, dists)
+	}
+
+	return len(items)
+}
+
+// GetNnsWithinRadius searches all shards in parallel and merges the results by raw distance.
+func (si *ShardedIndex[TV, TIX]) GetNnsWithinRadius(
+	vector []TV,
+	radius TV,
+	numNodesToInspect int,
+	ctx interfaces.AnnoyIndexContext[TV, TIX],
+) (result []TIX, distances []TV) {
+	candidates := si.fanOut(ctx, interfaces.SearchOptions[TIX]{}, func(
+		shard interfaces.AnnoyIndex[TV, TIX],
+		ctx interfaces.AnnoyIndexContext[TV, TIX],
+		_ interfaces.SearchOptions[TIX],
+	) ([]TIX, []TV, []TV) {
+		items, dists := shard.GetNnsWithinRadius(vector, radius, numNodesToInspect, ctx)
+
+		// Only normalized distances are returned, calculate the raw distances to merge
+		raw := make([]TV, len(items))
+
+		for i, local := range items {
+			raw[i] = si.rawDistance(vector, shard.GetItem(local))
+		}
+
+		return items, raw, dists
+	})
+
+	for _, c := range candidates {
+		result = append(result, c.item)
+		distances = append(distances, c.distance)
+	}
+
+	return
+}
+
+// GetNnsByVectors searches for each of the _vectors_ using up to _workers_ goroutines, where
+// each vector search is fanned out to all shards.
+func (si *ShardedIndex[TV, TIX]) GetNnsByVectors(
+	vectors [][]TV,
+	numReturn, numNodesToInspect, workers int,
+) (results [][]TIX, distances [][]TV) {
+	results = make([][]TIX, len(vectors))
+	distances = make([][]TV, len(vectors))
+
+	if workers == -1 {
+		workers = runtime.NumCPU()
+	} else if workers == 0 {
+		workers = 1
+	}
+
+	var wg sync.WaitGroup
+
+	queue := make(chan int, len(vectors))
+
+	for i := range vectors {
+		queue <- i
+	}
+
+	close(queue)
+
+	for w := 0; w < workers && w < len(vectors); w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			ctx := si.CreateContext()
+
+			for i := range queue {
+				results[i], distances[i] = si.GetNnsByVector(vectors[i], numReturn, numNodesToInspect, ctx)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return
+}
+
+// fanOut runs _search_ on all shards in parallel, translates the local items to global
+// items and returns all candidates sorted by raw distance. The _search_ returns the items,
+// their raw distances and the distances to return.
+func (si *ShardedIndex[TV, TIX]) fanOut(
+	ctx interfaces.AnnoyIndexContext[TV, TIX],
+	opts interfaces.SearchOptions[TIX],
+	search func(
+		shard interfaces.AnnoyIndex[TV, TIX],
+		ctx interfaces.AnnoyIndexContext[TV, TIX],
+		opts interfaces.SearchOptions[TIX],
+	) ([]TIX, []TV, []TV),
+) []candidate[TV, TIX] {
+	sc := ctx.(*shardedContext[TV, TIX])
+
+	var (
+		wg      sync.WaitGroup
+		results = make([][]candidate[TV, TIX], len(si.shards))
+	)
+
+	for i, shard := range si.shards {
+		wg.Add(1)
+
+		go func(i int, shard interfaces.AnnoyIndex[TV, TIX]) {
+			defer wg.Done()
+
+			globals := si.shardGlobals(i)
+			shardOpts := opts
+
+			if opts.Filter != nil {
+				shardOpts.Filter = func(local TIX) bool {
+					return int(local) < len(globals) && opts.Filter(globals[local])
+				}
+			}
+
+			items, raw, dists := search(shard, sc.contexts[i], shardOpts)
+
+			for j, local := range items {
+				if int(local) >= len(globals) {
+					// Added after the globals were read
+					continue
+				}
+
+				results[i] = append(results[i], candidate[TV, TIX]{
+					raw:      raw[j],
+					distance: dists[j],
+					item:     globals[local],
+				})
+			}
+		}(i, shard)
+	}
+
+	wg.Wait()
+
+	var candidates []candidate[TV, TIX]
+
+	for _, r := range results {
+		candidates = append(candidates, r...)
+	}
+
+	slices.SortFunc(candidates, func(a, b candidate[TV, TIX]) int {
+		switch {
+		case a.raw < b.raw:
+			return -1
+		case a.raw > b.raw:
+			return 1
+		case a.item < b.item:
+			return -1
+		case a.item > b.item:
+			return 1
+		}
+
+		return 0
+	})
+
+	return candidates
+}
@@ -11,8 +11,12 @@ const (
 	SideRight Side = 1
 )
 
+// VectorType is the element type of the vectors stored in the index.
+//
+// The `uint64` is only meaningful for the hamming distance, where each element
+// holds 64 packed bits.
 type VectorType interface {
-	float32 | float64
+	float32 | float64 | uint64
 }
 
 type Distance[TV VectorType, TIX IndexTypes] interface {
@@ -58,4 +62,16 @@ type Distance[TV VectorType, TIX IndexTypes] interface {
 	NodeSize() TIX
 	// VectorLength is the length of the vector the this distance operates on.
 	VectorLength() TIX
+	// Name is the name of the metric, e.g. "angular". It is recorded in the index file
+	// header and hence *must* be stable.
+	Name() string
+}
+
+// MarginBounder is optionally implemented by a `Distance` where the margin to a split plane
+// gives a lower bound of the normalized distance to all items on the other side of the plane.
+// It is used to prune sub-trees in radius searches.
+type MarginBounder[TV VectorType] interface {
+	// MarginBound returns the smallest possible normalized distance, from the vector that
+	// produced the _margin_, to any item on the other side of the split plane.
+	MarginBound(margin TV) TV
 }
@@ -1,7 +1,9 @@
 package interfaces
 
 import (
+	"context"
 	"io"
+	"io/fs"
 )
 
 type IndexTypes interface {
@@ -15,13 +17,23 @@ type AnnoyIndex[TV VectorType, TIX IndexTypes] interface {
 	io.Closer
 	// VectorLength returns the vector length of the index.
 	VectorLength() TIX
+	// GetNumberOfItems returns the number of items in the index.
+	GetNumberOfItems() TIX
+	// GetNumberOfTrees returns the number of trees in the index.
+	GetNumberOfTrees() int
 	// GetItem returns the vector of the given _itemIndex_.
 	GetItem(itemIndex TIX) []TV
 	// AddItem adds an item to the index. The ownership of the vector _v_ is taken
 	// by this function. The _itemIndex_ is a numbering index of the _v_ vector and
 	// *SHOULD* be incremental. If same _itemIndex_ is added twice, the last one
 	// will be the one in the index.
+	//
+	// It panics if the index is loaded, built or the vector length is wrong. Use `AddItemE`
+	// to get an error instead. In incremental mode, items added to a loaded or built index
+	// are kept in a delta segment until `Compact` is invoked.
 	AddItem(itemIndex TIX, v []TV)
+	// AddItemE is same as `AddItem` but returns an error instead of panicking.
+	AddItemE(itemIndex TIX, v []TV) error
 	// Build will build a a new index. The _numberOfTrees_ is the number of trees
 	// to build. The _numWorkers_ is the number of workers to use when building
 	// the index. If _numWorkers_ is -1, the number of workers will be set to the
@@ -30,16 +42,45 @@ type AnnoyIndex[TV VectorType, TIX IndexTypes] interface {
 	//
 	// The _numberOfTrees_ will be split amongst the workers. The more number
 	// of trees, the larger the index. But it also will be more precise.
+	//
+	// It panics if the index is loaded or already built. Use `BuildE` to get an error instead.
 	Build(numberOfTrees, numWorkers int)
+	// BuildE is same as `Build` but returns an error instead of panicking.
+	BuildE(numberOfTrees, numWorkers int) error
+	// BuildContext builds the index as `Build` does, but stops the workers when the
+	// _ctx_ is cancelled and reports the progress to `BuildOptions.Progress`.
+	//
+	// When cancelled, all trees built so far are dropped and the _ctx_ error is returned.
+	// The index is then left as it were before the build.
+	BuildContext(ctx context.Context, opts BuildOptions) error
+	// Unbuild drops all trees so that more items can be added and the index built
+	// again, possibly with a different number of trees. It is not possible to unbuild
+	// a loaded index.
+	Unbuild() error
+	// Compact rebuilds the trees, with the same number of trees as before, including the
+	// items added after `Build` when the index is in incremental mode and excluding the
+	// items marked as deleted.
+	Compact() error
+	// MarkDeleted marks the _items_ as deleted in a built or loaded index. Deleted items
+	// are skipped when searching and purged by `Compact`. The deletions are persisted in
+	// a `<file>.del` sidecar, that is rewritten on each invocation for a loaded index.
+	MarkDeleted(items ...TIX) error
+	// IsDeleted returns `true` if the _item_ is marked as deleted.
+	IsDeleted(item TIX) bool
+	// DeletedCount returns the number of items marked as deleted and not yet purged.
+	DeletedCount() int
 	// CreateContext will create a batch context, that should be used in subsequent
 	// calls to `GetNnsByVector` and `GetNnsByItem`.
 	//
-	// Create a new context per goroutine. Whenever a new index is loaded or saved,
-	// a new context *must* be created since it contains vital information about the
-	// index. Same applies when the index is *built!*
+	// Create a new context per goroutine, the index itself is safe for concurrent use. Whenever
+	// a new index is loaded or saved, a new context *should* be created since it is sized after
+	// the index. Same applies when the index is *built!* An outdated context is grown when used.
 	CreateContext() AnnoyIndexContext[TV, TIX]
 	// GetDistance returns the distance between the two given items.
 	GetDistance(i, j TIX) TV
+	// GetRawDistance returns the raw distance, as calculated by the `Distance`, between the
+	// two given items. The `GetDistance` is the normalized raw distance.
+	GetRawDistance(i, j TIX) TV
 	// GetNnsByItem will search for the closest vectors to the given _item_ in the index.
 	// When _numNodesToInspect_ is -1, it will search number of trees in index * _numReturn_.
 	GetNnsByItem(
@@ -47,6 +88,13 @@ type AnnoyIndex[TV VectorType, TIX IndexTypes] interface {
 		numReturn, numNodesToInspect int,
 		ctx AnnoyIndexContext[TV, TIX],
 	) (result []TIX, distances []TV)
+	// GetNnsByItemWithOptions is same as `GetNnsByItem` but with search _opts_.
+	GetNnsByItemWithOptions(
+		item TIX,
+		numReturn, numNodesToInspect int,
+		ctx AnnoyIndexContext[TV, TIX],
+		opts SearchOptions[TIX],
+	) (result []TIX, distances []TV)
 	// GetNnsByVector will search for the closest vectors to the given _vector_.
 	// When _numNodesToInspect_ is -1, it will search number of trees in index * _numReturn_.
 	GetNnsByVector(
@@ -54,10 +102,63 @@ type AnnoyIndex[TV VectorType, TIX IndexTypes] interface {
 		numReturn, numNodesToInspect int,
 		ctx AnnoyIndexContext[TV, TIX],
 	) (result []TIX, distances []TV)
+	// GetNnsByVectorWithOptions is same as `GetNnsByVector` but with search _opts_, such
+	// as a filter to exclude items from the result.
+	GetNnsByVectorWithOptions(
+		vector []TV,
+		numReturn, numNodesToInspect int,
+		ctx AnnoyIndexContext[TV, TIX],
+		opts SearchOptions[TIX],
+	) (result []TIX, distances []TV)
+	// GetNnsByVectorInto is same as `GetNnsByVector` but writes into the caller provided
+	// _result_ and _distances_ (may be `nil`) and returns the number of items written. At
+	// most `len(result)` items, or `len(distances)` if shorter, are returned. It does not
+	// allocate any memory once the _ctx_ has been used.
+	GetNnsByVectorInto(
+		vector []TV,
+		numNodesToInspect int,
+		result []TIX,
+		distances []TV,
+		ctx AnnoyIndexContext[TV, TIX],
+	) int
+	// GetNnsWithinRadius returns all items whose normalized distance to the _vector_ is at
+	// most _radius_, sorted by distance. When _numNodesToInspect_ is -1, there's no limit on
+	// the number of candidates to inspect.
+	GetNnsWithinRadius(
+		vector []TV,
+		radius TV,
+		numNodesToInspect int,
+		ctx AnnoyIndexContext[TV, TIX],
+	) (result []TIX, distances []TV)
+	// GetNnsByVectors searches for the closest items to each of the _vectors_ in parallel
+	// using up to _workers_ goroutines. The results are returned in the same order as the
+	// _vectors_. When _workers_ is -1, the number of CPU cores is used.
+	//
+	// No context is needed since it manages a pool of contexts internally.
+	GetNnsByVectors(
+		vectors [][]TV,
+		numReturn, numNodesToInspect, workers int,
+	) (results [][]TIX, distances [][]TV)
+	// Save writes the index to _fileName_, atomically replacing any existing file, and loads it.
 	Save(fileName string) error
+	// SaveTo writes the index, in the same format as `Save`, to _w_.
+	SaveTo(w io.Writer) error
 	Load(fileName string) error
+	// LoadBytes loads the index from _data_, e.g. embedded using `//go:embed`. The _data_
+	// must not be modified while loaded.
+	LoadBytes(data []byte) error
+	// LoadReaderAt reads _size_ bytes of index from _r_ into memory and loads it.
+	LoadReaderAt(r io.ReaderAt, size int64) error
+	// LoadFS loads the index file _name_, and its sidecars, from _fsys_, e.g. an `embed.FS`.
+	LoadFS(fsys fs.FS, name string) error
 }
 
 type AnnoyIndexBuilder interface {
-	ThreadBuild(treesPerWorker, workerIdx int, threadedBuildPolicy AnnoyIndexBuildPolicy)
+	// ThreadBuild builds _treesPerWorker_ trees on the current goroutine. It stops as soon as
+	// the _ctx_ is cancelled.
+	ThreadBuild(
+		ctx context.Context,
+		treesPerWorker, workerIdx int,
+		threadedBuildPolicy AnnoyIndexBuildPolicy,
+	)
 }
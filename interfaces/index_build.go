@@ -0,0 +1,29 @@
+package interfaces
+
+import "time"
+
+// BuildOptions is the options used when building an index using `BuildContext`.
+type BuildOptions struct {
+	// NumberOfTrees is the number of trees to build. If -1, trees are built until the
+	// index uses roughly twice the memory of the items.
+	NumberOfTrees int
+	// NumWorkers is the number of workers to use. If -1 (or any negative number), it is set
+	// to the number of CPU cores. If 0, it is set to 1 and hence run on the current goroutine.
+	NumWorkers int
+	// Progress is, when set, invoked each time a tree has been built. It may be invoked
+	// from any of the worker goroutines, but never concurrently. The index is locked while
+	// building and hence, it must not call back into the index.
+	Progress func(progress BuildProgress)
+}
+
+// BuildProgress is reported to `BuildOptions.Progress` while building an index.
+type BuildProgress struct {
+	// TreesBuilt is the number of trees built so far.
+	TreesBuilt int
+	// NumberOfTrees is the requested number of trees or -1 if decided automatically.
+	NumberOfTrees int
+	// NodesAllocated is the number of nodes, including the items, allocated so far.
+	NodesAllocated int
+	// Elapsed is the time elapsed since the build started.
+	Elapsed time.Duration
+}
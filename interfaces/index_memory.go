@@ -21,5 +21,16 @@ type BuildIndexAllocator interface {
 	// Free frees the memory allocated by the allocator.
 	Free()
 	//Reallocate will allocate/reallocate memory to fit the given size.
-	Reallocate(byteSize int) unsafe.Pointer
+	Reallocate(byteSize int) (unsafe.Pointer, error)
+}
+
+// FileBuildIndexAllocator is a `BuildIndexAllocator` that builds the index directly
+// into a file (on disk build). When the index is saved to the same file, nothing
+// needs to be written.
+type FileBuildIndexAllocator interface {
+	BuildIndexAllocator
+	// FileName is the file that the index is built into.
+	FileName() string
+	// Truncate will shrink the file to exactly _byteSize_ and flush it to disk.
+	Truncate(byteSize int) (unsafe.Pointer, error)
 }
@@ -0,0 +1,23 @@
+package interfaces
+
+// SearchOptions is the options used when searching using `GetNnsByVectorWithOptions`
+// or `GetNnsByItemWithOptions`.
+type SearchOptions[TIX IndexTypes] struct {
+	// Filter is, when set, invoked for each candidate item. Only items where it returns
+	// `true` are included in the result. For example, use the `Contains` method of a
+	// `utils.Bitset` as filter.
+	//
+	// When too few items passes the filter, the number of nodes to inspect is doubled
+	// until enough items are found or all nodes have been inspected.
+	Filter func(item TIX) bool
+	// OmitDistances skips calculating the distances and hence `nil` is returned as
+	// distances. Use this when only the items are needed.
+	OmitDistances bool
+	// RawDistances returns the raw distances, as calculated by `Distance.Distance`, instead
+	// of the normalized distances. For example, the angular raw distance is the squared
+	// normalized distance and hence no square root is calculated.
+	RawDistances bool
+	// QueryNormalized tells that the query vector is already normalized to unit length
+	// and hence the norm do not need to be calculated (angular distance).
+	QueryNormalized bool
+}
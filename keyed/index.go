@@ -0,0 +1,247 @@
+// Package keyed maps external keys, such as UUIDs or sparse ids, onto the dense item
+// indexes used by an `interfaces.AnnoyIndex`.
+package keyed
+
+import (
+	"bufio"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/mariotoffia/goannoy/interfaces"
+	"github.com/mariotoffia/goannoy/utils"
+)
+
+// keysSuffix is appended to the index file name to get the key map sidecar file.
+const keysSuffix = ".keys"
+
+// Index wraps an `interfaces.AnnoyIndex` and assigns dense item indexes to the keys
+// of type _K_. The key map is saved in a `<file>.keys` sidecar next to the index file.
+//
+// The key map is safe for concurrent use, keys may be added while searching.
+type Index[K comparable, TV interfaces.VectorType, TIX interfaces.IndexTypes] struct {
+	index interfaces.AnnoyIndex[TV, TIX]
+	// lock guards _keys_ and _ids_.
+	lock sync.RWMutex
+	// keys is the key of each item index.
+	keys []K
+	// ids maps a key to the item index.
+	ids map[K]TIX
+}
+
+// New creates a new keyed index wrapping the _index_. The _index_ must be empty or
+// loaded using `Load` on the keyed index.
+func New[K comparable, TV interfaces.VectorType, TIX interfaces.IndexTypes](
+	index interfaces.AnnoyIndex[TV, TIX],
+) *Index[K, TV, TIX] {
+	return &Index[K, TV, TIX]{
+		index: index,
+		ids:   map[K]TIX{},
+	}
+}
+
+// Index returns the wrapped index.
+func (ki *Index[K, TV, TIX]) Index() interfaces.AnnoyIndex[TV, TIX] {
+	return ki.index
+}
+
+// Close closes the wrapped index and clears the key map.
+func (ki *Index[K, TV, TIX]) Close() error {
+	ki.lock.Lock()
+	ki.keys = nil
+	ki.ids = map[K]TIX{}
+	ki.lock.Unlock()
+
+	return ki.index.Close()
+}
+
+// AddKey adds the vector _v_ under the _key_. If the key already exists, the item is
+// replaced, otherwise it is assigned the next item index.
+func (ki *Index[K, TV, TIX]) AddKey(key K, v []TV) error {
+	ki.lock.Lock()
+	defer ki.lock.Unlock()
+
+	id, ok := ki.ids[key]
+
+	if !ok {
+		id = TIX(len(ki.keys))
+	}
+
+	if err := ki.index.AddItemE(id, v); err != nil {
+		return err
+	}
+
+	if !ok {
+		ki.ids[key] = id
+		ki.keys = append(ki.keys, key)
+	}
+
+	return nil
+}
+
+// Build builds the wrapped index. See `interfaces.AnnoyIndex.BuildE`.
+func (ki *Index[K, TV, TIX]) Build(numberOfTrees, numWorkers int) error {
+	return ki.index.BuildE(numberOfTrees, numWorkers)
+}
+
+// CreateContext creates a context for the wrapped index to be used when searching.
+func (ki *Index[K, TV, TIX]) CreateContext() interfaces.AnnoyIndexContext[TV, TIX] {
+	return ki.index.CreateContext()
+}
+
+// DeleteKey marks the item of the _key_ as deleted. See `interfaces.AnnoyIndex.MarkDeleted`.
+func (ki *Index[K, TV, TIX]) DeleteKey(key K) error {
+	id, ok := ki.ID(key)
+
+	if !ok {
+		return fmt.Errorf("key %v do not exist", key)
+	}
+
+	return ki.index.MarkDeleted(id)
+}
+
+// ID returns the item index of the _key_.
+func (ki *Index[K, TV, TIX]) ID(key K) (TIX, bool) {
+	ki.lock.RLock()
+	defer ki.lock.RUnlock()
+
+	id, ok := ki.ids[key]
+	return id, ok
+}
+
+// Key returns the key of the item index _id_.
+func (ki *Index[K, TV, TIX]) Key(id TIX) (K, bool) {
+	ki.lock.RLock()
+	defer ki.lock.RUnlock()
+
+	if int(id) >= len(ki.keys) {
+		var empty K
+		return empty, false
+	}
+
+	return ki.keys[id], true
+}
+
+// GetItemByKey returns the vector of the _key_.
+func (ki *Index[K, TV, TIX]) GetItemByKey(key K) ([]TV, bool) {
+	id, ok := ki.ID(key)
+
+	if !ok {
+		return nil, false
+	}
+
+	return ki.index.GetItem(id), true
+}
+
+// GetNnsByKey searches for the closest items to the item of the _key_ and returns their keys.
+func (ki *Index[K, TV, TIX]) GetNnsByKey(
+	key K,
+	numReturn, numNodesToInspect int,
+	ctx interfaces.AnnoyIndexContext[TV, TIX],
+) (result []K, distances []TV, err error) {
+	id, ok := ki.ID(key)
+
+	if !ok {
+		return nil, nil, fmt.Errorf("key %v do not exist", key)
+	}
+
+	ids, distances := ki.index.GetNnsByItem(id, numReturn, numNodesToInspect, ctx)
+	result, distances = ki.toKeys(ids, distances)
+
+	return result, distances, nil
+}
+
+// GetNnsByVector searches for the closest items to the _vector_ and returns their keys.
+func (ki *Index[K, TV, TIX]) GetNnsByVector(
+	vector []TV,
+	numReturn, numNodesToInspect int,
+	ctx interfaces.AnnoyIndexContext[TV, TIX],
+) (result []K, distances []TV) {
+	ids, distances := ki.index.GetNnsByVector(vector, numReturn, numNodesToInspect, ctx)
+
+	return ki.toKeys(ids, distances)
+}
+
+// Save saves the wrapped index to _fileName_ and the key map to `<fileName>.keys`.
+func (ki *Index[K, TV, TIX]) Save(fileName string) error {
+	ki.lock.RLock()
+	defer ki.lock.RUnlock()
+
+	if err := ki.index.Save(fileName); err != nil {
+		return err
+	}
+
+	return utils.WriteFileAtomic(fileName+keysSuffix, func(w io.Writer) error {
+		return gob.NewEncoder(w).Encode(ki.keys)
+	})
+}
+
+// Load loads the wrapped index from _fileName_ and the key map from `<fileName>.keys`. It is an
+// error if the number of keys differs from the number of items in the index.
+func (ki *Index[K, TV, TIX]) Load(fileName string) error {
+	ki.lock.Lock()
+	defer ki.lock.Unlock()
+
+	file, err := os.Open(fileName + keysSuffix)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	var keys []K
+
+	if err := gob.NewDecoder(bufio.NewReader(file)).Decode(&keys); err != nil {
+		return fmt.Errorf("failed to read keys from %s: %w", file.Name(), err)
+	}
+
+	if err := ki.index.Load(fileName); err != nil {
+		return err
+	}
+
+	if n := ki.index.GetNumberOfItems(); len(keys) != int(n) {
+		return fmt.Errorf(
+			"number of keys in %s do not match the index: %d != %d", file.Name(), len(keys), n,
+		)
+	}
+
+	ki.keys = keys
+	ki.ids = make(map[K]TIX, len(keys))
+
+	for id, key := range keys {
+		ki.ids[key] = TIX(id)
+	}
+
+	return nil
+}
+
+// toKeys returns the keys of the _ids_ and their _distances_. Items without a key are
+// dropped.
+func (ki *Index[K, TV, TIX]) toKeys(ids []TIX, distances []TV) ([]K, []TV) {
+	if ids == nil {
+		return nil, distances
+	}
+
+	ki.lock.RLock()
+	defer ki.lock.RUnlock()
+
+	keys := make([]K, 0, len(ids))
+	n := 0
+
+	for i, id := range ids {
+		if int(id) >= len(ki.keys) {
+			continue
+		}
+
+		keys = append(keys, ki.keys[id])
+
+		if i < len(distances) {
+			distances[n] = distances[i]
+			n++
+		}
+	}
+
+	return keys, distances[:n]
+}
@@ -0,0 +1,136 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"unsafe"
+
+	"github.com/mariotoffia/goannoy/interfaces"
+	"github.com/mariotoffia/goannoy/utils"
+)
+
+const (
+	// fileVersion is the current version of the file format.
+	fileVersion = uint32(1)
+	// fieldNameSize is the maximum length, in bytes, of a field name.
+	fieldNameSize = 32
+)
+
+// fileMagic is the first bytes of a metadata file.
+var fileMagic = [8]byte{'G', 'O', 'A', 'N', 'M', 'E', 'T', 'A'}
+
+// fileHeader is written in front of the records. It is followed by one zero padded name
+// per field and then the records. All values are little endian and, since the header and
+// names are multiples of eight bytes, the records are aligned when memory mapped.
+type fileHeader struct {
+	Magic      [8]byte
+	Version    uint32
+	FieldCount uint32
+	ItemCount  uint64
+}
+
+// Save writes the store to _fileName_, e.g. the index file name with `Suffix` appended. The
+// file is atomically replaced and hence, a loaded store may be saved to the file it was loaded from.
+func (s *Store[TIX]) Save(fileName string) error {
+	return utils.WriteFileAtomic(fileName, s.saveTo)
+}
+
+// saveTo writes the header, field names and records to _w_.
+func (s *Store[TIX]) saveTo(w io.Writer) error {
+	hdr := fileHeader{
+		Magic:      fileMagic,
+		Version:    fileVersion,
+		FieldCount: uint32(len(s.fields)),
+		ItemCount:  uint64(s.Len()),
+	}
+
+	if err := binary.Write(w, binary.LittleEndian, &hdr); err != nil {
+		return err
+	}
+
+	for _, f := range s.fields {
+		var name [fieldNameSize]byte
+
+		copy(name[:], f)
+
+		if _, err := w.Write(name[:]); err != nil {
+			return err
+		}
+	}
+
+	return binary.Write(w, binary.LittleEndian, s.records)
+}
+
+// Load opens the store saved in _fileName_ using the _allocator_. The loaded store is
+// read-only and must be closed using `Close`.
+func Load[TIX interfaces.IndexTypes](
+	fileName string,
+	allocator interfaces.IndexAllocator,
+) (*Store[TIX], error) {
+	memory, err := allocator.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := mapStore[TIX](memory.Ptr(), memory.Size())
+	if err != nil {
+		memory.Close()
+		return nil, fmt.Errorf("%s: %w", fileName, err)
+	}
+
+	s.memory = memory
+
+	return s, nil
+}
+
+// mapStore maps a store onto the memory _ptr_ of _size_ bytes.
+func mapStore[TIX interfaces.IndexTypes](ptr unsafe.Pointer, size int64) (*Store[TIX], error) {
+	hdrSize := int64(binary.Size(fileHeader{}))
+
+	if size < hdrSize {
+		return nil, fmt.Errorf("metadata file is too small")
+	}
+
+	var hdr fileHeader
+
+	err := binary.Read(
+		bytes.NewReader(unsafe.Slice((*byte)(ptr), hdrSize)), binary.LittleEndian, &hdr,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if hdr.Magic != fileMagic {
+		return nil, fmt.Errorf("not a metadata file")
+	}
+
+	if hdr.Version == 0 || hdr.Version > fileVersion {
+		return nil, fmt.Errorf("unsupported metadata version %d", hdr.Version)
+	}
+
+	namesSize := int64(hdr.FieldCount) * fieldNameSize
+	count := int64(hdr.FieldCount) * int64(hdr.ItemCount)
+
+	if hdr.FieldCount == 0 || size != hdrSize+namesSize+count*8 {
+		return nil, fmt.Errorf(
+			"metadata file size %d do not match %d fields and %d items",
+			size, hdr.FieldCount, hdr.ItemCount,
+		)
+	}
+
+	s := &Store[TIX]{}
+
+	names := unsafe.Slice((*byte)(unsafe.Add(ptr, hdrSize)), namesSize)
+
+	for i := int64(0); i < int64(hdr.FieldCount); i++ {
+		name := names[i*fieldNameSize : (i+1)*fieldNameSize]
+		s.fields = append(s.fields, string(bytes.TrimRight(name, "\x00")))
+	}
+
+	s.records = mapRecords(unsafe.Add(ptr, hdrSize+namesSize), int(count))
+
+	return s, nil
+}
@@ -0,0 +1,130 @@
+package metadata
+
+import "fmt"
+
+type op int
+
+const (
+	opEq op = iota
+	opRange
+	opIn
+	opAnd
+	opOr
+	opNot
+)
+
+// Predicate is a condition on the attributes of an item. Use `Store.Filter` to compile
+// it into a search filter.
+type Predicate struct {
+	op     op
+	field  string
+	values []int64
+	preds  []Predicate
+}
+
+// Eq matches items where _field_ equals _value_.
+func Eq(field string, value int64) Predicate {
+	return Predicate{op: opEq, field: field, values: []int64{value}}
+}
+
+// Range matches items where _field_ is in the closed range [_min_, _max_].
+func Range(field string, min, max int64) Predicate {
+	return Predicate{op: opRange, field: field, values: []int64{min, max}}
+}
+
+// In matches items where _field_ equals any of the _values_.
+func In(field string, values ...int64) Predicate {
+	return Predicate{op: opIn, field: field, values: values}
+}
+
+// And matches items matching all _preds_.
+func And(preds ...Predicate) Predicate {
+	return Predicate{op: opAnd, preds: preds}
+}
+
+// Or matches items matching any of the _preds_.
+func Or(preds ...Predicate) Predicate {
+	return Predicate{op: opOr, preds: preds}
+}
+
+// Not matches items not matching _pred_.
+func Not(pred Predicate) Predicate {
+	return Predicate{op: opNot, preds: []Predicate{pred}}
+}
+
+// compile resolves the field names using _field_ and returns a function matching a record.
+func (p Predicate) compile(field func(name string) (int, bool)) (func(record []int64) bool, error) {
+	switch p.op {
+	case opAnd, opOr, opNot:
+		matchers := make([]func(record []int64) bool, len(p.preds))
+
+		for i, pred := range p.preds {
+			m, err := pred.compile(field)
+			if err != nil {
+				return nil, err
+			}
+
+			matchers[i] = m
+		}
+
+		switch p.op {
+		case opAnd:
+			return func(record []int64) bool {
+				for _, m := range matchers {
+					if !m(record) {
+						return false
+					}
+				}
+
+				return true
+			}, nil
+		case opOr:
+			return func(record []int64) bool {
+				for _, m := range matchers {
+					if m(record) {
+						return true
+					}
+				}
+
+				return false
+			}, nil
+		default:
+			m := matchers[0]
+
+			return func(record []int64) bool {
+				return !m(record)
+			}, nil
+		}
+	}
+
+	f, ok := field(p.field)
+	if !ok {
+		return nil, fmt.Errorf("unknown metadata field %q", p.field)
+	}
+
+	switch p.op {
+	case opEq:
+		v := p.values[0]
+
+		return func(record []int64) bool {
+			return record[f] == v
+		}, nil
+	case opRange:
+		min, max := p.values[0], p.values[1]
+
+		return func(record []int64) bool {
+			return record[f] >= min && record[f] <= max
+		}, nil
+	default:
+		set := make(map[int64]struct{}, len(p.values))
+
+		for _, v := range p.values {
+			set[v] = struct{}{}
+		}
+
+		return func(record []int64) bool {
+			_, ok := set[record[f]]
+			return ok
+		}, nil
+	}
+}
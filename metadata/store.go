@@ -0,0 +1,142 @@
+// Package metadata stores small, fixed width, attributes per item next to an index and
+// compiles attribute predicates into search filters.
+//
+// Each item has one `int64` value per field, hence categories, tenants and timestamps
+// must be encoded as integers. The store is saved as a sidecar file and loaded using an
+// `interfaces.IndexAllocator`, i.e. memory mapped or read into GC memory.
+package metadata
+
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/mariotoffia/goannoy/interfaces"
+)
+
+// Suffix is the conventional suffix of a metadata sidecar, i.e. `<index file>.meta`.
+const Suffix = ".meta"
+
+// Store holds the attributes of each item.
+type Store[TIX interfaces.IndexTypes] struct {
+	fields []string
+	// records is all values, item by item, i.e. `len(fields)` values per item.
+	records []int64
+	// memory is set when loaded and hence the records are read-only.
+	memory interfaces.AllocatedIndex
+}
+
+// New creates an empty, writeable, store with the given _fields_.
+func New[TIX interfaces.IndexTypes](fields ...string) *Store[TIX] {
+	if len(fields) == 0 {
+		panic("metadata requires at least one field")
+	}
+
+	for _, f := range fields {
+		if len(f) > fieldNameSize {
+			panic(fmt.Sprintf("field name %q is longer than %d bytes", f, fieldNameSize))
+		}
+	}
+
+	return &Store[TIX]{fields: append([]string(nil), fields...)}
+}
+
+// Fields returns the field names in the order they are stored.
+func (s *Store[TIX]) Fields() []string {
+	return s.fields
+}
+
+// Field returns the position of the field _name_.
+func (s *Store[TIX]) Field(name string) (int, bool) {
+	for i, f := range s.fields {
+		if f == name {
+			return i, true
+		}
+	}
+
+	return -1, false
+}
+
+// Len returns the number of items in the store. Items are numbered from zero,
+// hence items never set have all values zero.
+func (s *Store[TIX]) Len() int {
+	return len(s.records) / len(s.fields)
+}
+
+// Set sets all _values_, one per field in field order, of the _item_.
+func (s *Store[TIX]) Set(item TIX, values ...int64) error {
+	if s.memory != nil {
+		return fmt.Errorf("can't modify a loaded metadata store")
+	}
+
+	if len(values) != len(s.fields) {
+		return fmt.Errorf("expected %d values, got %d", len(s.fields), len(values))
+	}
+
+	end := (int(item) + 1) * len(s.fields)
+
+	if end > len(s.records) {
+		s.records = append(s.records, make([]int64, end-len(s.records))...)
+	}
+
+	copy(s.records[end-len(s.fields):end], values)
+
+	return nil
+}
+
+// Get returns the value of the _field_, by position, for the _item_. Zero is returned
+// for items not in the store.
+func (s *Store[TIX]) Get(item TIX, field int) int64 {
+	if record := s.record(item); record != nil {
+		return record[field]
+	}
+
+	return 0
+}
+
+// Filter compiles the _predicate_ into a filter usable as `interfaces.SearchOptions.Filter`.
+// Items not in the store never pass the filter.
+func (s *Store[TIX]) Filter(predicate Predicate) (func(item TIX) bool, error) {
+	match, err := predicate.compile(s.Field)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(item TIX) bool {
+		record := s.record(item)
+		return record != nil && match(record)
+	}, nil
+}
+
+// Close releases the memory of a loaded store.
+func (s *Store[TIX]) Close() error {
+	s.records = nil
+
+	if s.memory == nil {
+		return nil
+	}
+
+	err := s.memory.Close()
+	s.memory = nil
+
+	return err
+}
+
+// record returns the values of the _item_ or `nil` if not in the store.
+func (s *Store[TIX]) record(item TIX) []int64 {
+	start := int(item) * len(s.fields)
+
+	if start+len(s.fields) > len(s.records) {
+		return nil
+	}
+
+	return s.records[start : start+len(s.fields) : start+len(s.fields)]
+}
+
+// mapRecords points the records onto the _ptr_ holding _count_ values.
+func mapRecords(ptr unsafe.Pointer, count int) []int64 {
+	if count == 0 {
+		return nil
+	}
+
+	return unsafe.Slice((*int64)(ptr), count)
+}
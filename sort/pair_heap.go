@@ -0,0 +1,86 @@
+package sort
+
+import (
+	"github.com/mariotoffia/goannoy/interfaces"
+	"golang.org/x/exp/constraints"
+)
+
+// PairHeap is a priority queue, with the same ordering as `PriorityQueue`, that stores
+// the pairs by value. Use `Reset` to reuse the underlying memory and hence it do not
+// allocate once it has grown to the needed capacity.
+type PairHeap[T constraints.Ordered, S constraints.Ordered] struct {
+	pairs []interfaces.Pair[T, S]
+}
+
+// Reset empties the heap but keeps the memory.
+func (h *PairHeap[_, _]) Reset() {
+	h.pairs = h.pairs[:0]
+}
+
+func (h *PairHeap[_, _]) Len() int {
+	return len(h.pairs)
+}
+
+func (h *PairHeap[_, _]) Empty() bool {
+	return len(h.pairs) == 0
+}
+
+func (h *PairHeap[T, S]) Push(first T, second S) {
+	h.pairs = append(h.pairs, interfaces.Pair[T, S]{First: first, Second: second})
+	h.up(len(h.pairs) - 1)
+}
+
+// Pop removes and returns the top pair. It panics if the heap is empty.
+func (h *PairHeap[T, S]) Pop() interfaces.Pair[T, S] {
+	n := len(h.pairs) - 1
+	top := h.pairs[0]
+
+	h.pairs[0] = h.pairs[n]
+	h.pairs = h.pairs[:n]
+
+	if n > 0 {
+		h.down(0)
+	}
+
+	return top
+}
+
+func (h *PairHeap[T, S]) less(i, j int) bool {
+	return h.pairs[i].Less(&h.pairs[j])
+}
+
+func (h *PairHeap[T, S]) up(j int) {
+	for j > 0 {
+		i := (j - 1) / 2 // parent
+
+		if !h.less(j, i) {
+			break
+		}
+
+		h.pairs[i], h.pairs[j] = h.pairs[j], h.pairs[i]
+		j = i
+	}
+}
+
+func (h *PairHeap[T, S]) down(i int) {
+	n := len(h.pairs)
+
+	for {
+		j := 2*i + 1 // left child
+
+		if j >= n {
+			break
+		}
+
+		if r := j + 1; r < n && h.less(r, j) {
+			j = r
+		}
+
+		if !h.less(j, i) {
+			break
+		}
+
+		h.pairs[i], h.pairs[j] = h.pairs[j], h.pairs[i]
+		i = j
+	}
+}
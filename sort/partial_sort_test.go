@@ -11,12 +11,12 @@ import (
 
 func TestCorrectness(t *testing.T) {
 	var s = interfaces.Pairs[float32, uint32]{
-		{10, 10},
-		{6, 6},
-		{7, 7},
-		{8, 8},
-		{5, 5},
-		{1, 1},
+		{First: 10, Second: 10},
+		{First: 6, Second: 6},
+		{First: 7, Second: 7},
+		{First: 8, Second: 8},
+		{First: 5, Second: 5},
+		{First: 1, Second: 1},
 	}
 
 	PartialSortSlice(s, 0, 3, len(s))
@@ -33,12 +33,12 @@ func TestCorrectness(t *testing.T) {
 
 func TestCorrectness2(t *testing.T) {
 	var s = interfaces.Pairs[float32, uint32]{
-		{10, 10},
-		{6, 6},
-		{7, 7},
-		{8, 8},
-		{5, 5},
-		{1, 1},
+		{First: 10, Second: 10},
+		{First: 6, Second: 6},
+		{First: 7, Second: 7},
+		{First: 8, Second: 8},
+		{First: 5, Second: 5},
+		{First: 1, Second: 1},
 	}
 
 	PartialSortSlice2(s, 0, 3, len(s))
@@ -55,10 +55,9 @@ func TestCorrectness2(t *testing.T) {
 
 func TestCorrectnessBug(t *testing.T) {
 	var s = []*interfaces.Pair[float32, uint32]{
-		{1.1055728, 0},
-		{2, 1},
-		{
-			0.21114564, 2},
+		{First: 1.1055728, Second: 0},
+		{First: 2, Second: 1},
+		{First: 0.21114564, Second: 2},
 	}
 
 	PartialSortSlice(s, 0, 3, len(s))
@@ -69,10 +68,9 @@ func TestCorrectnessBug(t *testing.T) {
 
 func TestCorrectnessBug2(t *testing.T) {
 	var s = []*interfaces.Pair[float32, uint32]{
-		{1.1055728, 0},
-		{2, 1},
-		{
-			0.21114564, 2},
+		{First: 1.1055728, Second: 0},
+		{First: 2, Second: 1},
+		{First: 0.21114564, Second: 2},
 	}
 
 	PartialSortSlice2(s, 0, 3, len(s))
@@ -27,7 +27,7 @@ func (pq *PriorityQueue[_, _]) Empty() bool {
 }
 
 func (pq *PriorityQueue[T, S]) Push(first T, second S) {
-	heap.Push(&pq.pq, &interfaces.Pair[T, S]{first, second})
+	heap.Push(&pq.pq, &interfaces.Pair[T, S]{First: first, Second: second})
 }
 
 func (pq *PriorityQueue[T, S]) Pop() *interfaces.Pair[T, S] {
@@ -29,3 +29,27 @@ func TestSimple(t *testing.T) {
 		expectFirst = float32(int(expectFirst*10)) / 10
 	}
 }
+
+func TestPairHeapSameOrderAsPriorityQueue(t *testing.T) {
+	pq := sort.NewPriorityQueue[float32, int]()
+
+	var h sort.PairHeap[float32, int]
+
+	for i, v := range []float32{5, 1, 4, 1, 3, 9, 2, 6} {
+		pq.Push(v, i)
+		h.Push(v, i)
+	}
+
+	for !pq.Empty() {
+		expected := pq.Pop()
+		actual := h.Pop()
+
+		assert.Equal(t, *expected, actual)
+	}
+
+	assert.True(t, h.Empty())
+
+	h.Push(1, 1)
+	h.Reset()
+	assert.Equal(t, 0, h.Len())
+}
@@ -1,14 +1,16 @@
 package sort
 
 import (
+	"slices"
 	"sort"
 
 	"github.com/jfcg/sorty/v2"
 	"github.com/mariotoffia/goannoy/interfaces"
 )
 
+// SortSlice sorts the _slice_ in ascending order. It does not allocate.
 func SortSlice[TIX interfaces.IndexTypes](slice []TIX) {
-	sorty.SortSlice(slice)
+	slices.Sort(slice)
 }
 
 func SortSlice2[TIX interfaces.IndexTypes](slice []TIX) {
@@ -29,11 +31,20 @@ func SortSlice3[TIX interfaces.IndexTypes](slice []TIX) {
 	}
 }
 
+// SortPairs sorts the _pairs_ in ascending order. It does not allocate.
 func SortPairs[TV interfaces.VectorType, TIX interfaces.IndexTypes](
 	pairs []*interfaces.Pair[TV, TIX],
 ) {
-	sort.Slice(pairs, func(i, j int) bool {
-		return pairs[i].Less(pairs[j])
+	slices.SortFunc(pairs, func(a, b *interfaces.Pair[TV, TIX]) int {
+		if a.Less(b) {
+			return -1
+		}
+
+		if b.Less(a) {
+			return 1
+		}
+
+		return 0
 	})
 	/*
 	   	lsw := func(i, k, r, s int) bool {
@@ -0,0 +1,127 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mariotoffia/goannoy/builder"
+	"github.com/mariotoffia/goannoy/index"
+	"github.com/mariotoffia/goannoy/interfaces"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The fixtures in testdata are hand-crafted with the exact memory layout of Spotify
+// Annoy (int32 indexes, float vectors, no padding other than the C++ struct alignment)
+// for the items (1, 0), (0, 2) and (3, 3) and a single tree. Since three items fit
+// into a single node, the root is a leaf node listing all items and it is followed by
+// a copy of the root, exactly as `AnnoyIndex::build` and `AnnoyIndex::save` produces.
+//
+//	angular:   int32 n_descendants, union {int32 children[2]; float norm}, float v[2]
+//	euclidean: int32 n_descendants, float a, int32 children[2], float v[2]
+//	dot:       int32 n_descendants, int32 children[2], float dot_factor, float v[2]
+//
+// The dot_factor is calculated with float (not double) arithmetic, as Annoy does.
+var annoyFixtureItems = [][]float32{{1, 0}, {0, 2}, {3, 3}}
+
+func annoyCompatibleIndex(metric string) interfaces.AnnoyIndex[float32, uint32] {
+	bld := builder.Index[float32, uint32]().AnnoyCompatible()
+
+	switch metric {
+	case "angular":
+		bld.AngularDistance(2)
+	case "euclidean":
+		bld.EuclideanDistance(2)
+	case "dot":
+		bld.DotProductDistance(2)
+	}
+
+	return bld.Build()
+}
+
+func TestAnnoyFixturesAreLoaded(t *testing.T) {
+	expected := map[string][]uint32{
+		"angular":   {0, 2, 1},
+		"euclidean": {0, 1, 2},
+		"dot":       {2, 0, 1},
+	}
+
+	for metric, nns := range expected {
+		t.Run(metric, func(t *testing.T) {
+			idx := annoyCompatibleIndex(metric)
+			defer idx.Close()
+
+			require.NoError(t, idx.Load(filepath.Join("testdata", metric+"_f2_n3.ann")))
+
+			for i, v := range annoyFixtureItems {
+				assert.Equal(t, v, idx.GetItem(uint32(i)))
+			}
+
+			result, _ := idx.GetNnsByVector([]float32{2, 0}, 3, -1, idx.CreateContext())
+			assert.Equal(t, nns, result)
+		})
+	}
+}
+
+func TestAnnoySaveIsByteForByteCompatible(t *testing.T) {
+	for _, metric := range []string{"angular", "euclidean", "dot"} {
+		t.Run(metric, func(t *testing.T) {
+			idx := annoyCompatibleIndex(metric)
+			defer idx.Close()
+
+			for i, v := range annoyFixtureItems {
+				idx.AddItem(uint32(i), v)
+			}
+
+			idx.Build(1, -1)
+
+			fileName := filepath.Join(t.TempDir(), metric+".ann")
+			require.NoError(t, idx.Save(fileName))
+
+			expected, err := os.ReadFile(filepath.Join("testdata", metric+"_f2_n3.ann"))
+			require.NoError(t, err)
+
+			actual, err := os.ReadFile(fileName)
+			require.NoError(t, err)
+
+			assert.Equal(t, expected, actual)
+		})
+	}
+}
+
+func TestAnnoyCompatibilityRejectsIncompatibleTypes(t *testing.T) {
+	wide := builder.Index[float64, uint32]().AngularDistance(2).AnnoyCompatible().Build()
+	defer wide.Close()
+
+	wide.AddItem(0, []float64{1, 0})
+	assert.ErrorIs(t, wide.BuildE(1, -1), index.ErrNotAnnoyCompatible)
+	assert.ErrorIs(
+		t, wide.Load(filepath.Join("testdata", "angular_f2_n3.ann")), index.ErrNotAnnoyCompatible,
+	)
+
+	header := builder.Index[float32, uint32]().AngularDistance(2).AnnoyCompatible().FileHeader().Build()
+	defer header.Close()
+
+	header.AddItem(0, []float32{1, 0})
+	assert.ErrorIs(t, header.BuildE(1, -1), index.ErrNotAnnoyCompatible)
+}
+
+func TestAnnoyCompatibilityRejectsFilesWithHeader(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "header.ann")
+
+	idx := builder.Index[float32, uint32]().AngularDistance(2).FileHeader().Build()
+	defer idx.Close()
+
+	for i, v := range annoyFixtureItems {
+		idx.AddItem(uint32(i), v)
+	}
+
+	idx.Build(1, -1)
+	require.NoError(t, idx.Save(fileName))
+
+	compat := annoyCompatibleIndex("angular")
+	defer compat.Close()
+
+	assert.ErrorIs(t, compat.Load(fileName), index.ErrNotAnnoyCompatible)
+}
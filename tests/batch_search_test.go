@@ -0,0 +1,38 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/mariotoffia/goannoy/builder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetNnsByVectorsReturnsResultsInInputOrder(t *testing.T) {
+	idx := builder.Index[float32, uint32]().AngularDistance(8).Build()
+	defer idx.Close()
+
+	addRandomItems(idx, 500, 8)
+	idx.Build(10, -1)
+
+	vectors := make([][]float32, 100)
+	for i := range vectors {
+		vectors[i] = idx.GetItem(uint32(i * 5))
+	}
+
+	ctx := idx.CreateContext()
+
+	for _, workers := range []int{-1, 0, 1, 4, 200} {
+		results, distances := idx.GetNnsByVectors(vectors, 10, -1, workers)
+
+		require.Len(t, results, len(vectors))
+		require.Len(t, distances, len(vectors))
+
+		for i := range vectors {
+			expected, expectedDistances := idx.GetNnsByVector(vectors[i], 10, -1, ctx)
+
+			assert.Equal(t, expected, results[i], "workers: %d, vector: %d", workers, i)
+			assert.Equal(t, expectedDistances, distances[i], "workers: %d, vector: %d", workers, i)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mariotoffia/goannoy/builder"
+	"github.com/mariotoffia/goannoy/interfaces"
+	"github.com/mariotoffia/goannoy/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildContextReportsProgress(t *testing.T) {
+	idx := builder.Index[float32, uint32]().
+		AngularDistance(8).
+		UseMultiWorkerPolicy().
+		Build()
+
+	defer idx.Close()
+
+	addRandomItems(idx, 200, 8)
+
+	var reports []interfaces.BuildProgress
+
+	err := idx.BuildContext(context.Background(), interfaces.BuildOptions{
+		NumberOfTrees: 10,
+		NumWorkers:    4,
+		Progress: func(progress interfaces.BuildProgress) {
+			reports = append(reports, progress)
+		},
+	})
+
+	require.NoError(t, err)
+	require.Len(t, reports, 10)
+
+	for i, report := range reports {
+		assert.Equal(t, i+1, report.TreesBuilt)
+		assert.Equal(t, 10, report.NumberOfTrees)
+		assert.Greater(t, report.NodesAllocated, 200)
+	}
+
+	assert.Equal(t, 10, idx.GetNumberOfTrees())
+}
+
+func TestBuildContextDefaultsNumWorkers(t *testing.T) {
+	for _, numWorkers := range []int{0, -1, -2} {
+		idx := builder.Index[float32, uint32]().
+			AngularDistance(8).
+			UseMultiWorkerPolicy().
+			Build()
+
+		addRandomItems(idx, 200, 8)
+
+		err := idx.BuildContext(context.Background(), interfaces.BuildOptions{
+			NumberOfTrees: 10,
+			NumWorkers:    numWorkers,
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, 10, idx.GetNumberOfTrees(), "NumWorkers: %d", numWorkers)
+
+		idx.Close()
+	}
+}
+
+func TestBuildContextCancelledRollsBack(t *testing.T) {
+	idx := builder.Index[float32, uint32]().AngularDistance(8).Build()
+	defer idx.Close()
+
+	addRandomItems(idx, 200, 8)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	err := idx.BuildContext(ctx, interfaces.BuildOptions{
+		NumberOfTrees: 100,
+		NumWorkers:    0,
+		Progress: func(progress interfaces.BuildProgress) {
+			if progress.TreesBuilt == 3 {
+				cancel()
+			}
+		},
+	})
+
+	assert.True(t, errors.Is(err, context.Canceled), "%v", err)
+	assert.Equal(t, 0, idx.GetNumberOfTrees())
+	assert.Equal(t, uint32(200), idx.GetNumberOfItems())
+
+	// The index is left unbuilt and may be built again
+	require.NoError(t, idx.BuildE(5, -1))
+	assert.Equal(t, 5, idx.GetNumberOfTrees())
+}
+
+func addRandomItems(idx interfaces.AnnoyIndex[float32, uint32], numItems, vectorLength int) {
+	rnd := random.NewGoRandom()
+
+	for i := 0; i < numItems; i++ {
+		v := make([]float32, vectorLength)
+
+		for j := range v {
+			v[j] = float32(rnd.NormFloat64())
+		}
+
+		idx.AddItem(uint32(i), v)
+	}
+}
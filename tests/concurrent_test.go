@@ -0,0 +1,149 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mariotoffia/goannoy/builder"
+	"github.com/mariotoffia/goannoy/index/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConcurrentSearches(t *testing.T) {
+	idx := builder.Index[float32, uint32]().
+		EuclideanDistance(4).
+		UseMultiWorkerPolicy().
+		Build()
+	defer idx.Close()
+
+	addRandomItems(idx, 2000, 4)
+	idx.Build(5, -1)
+
+	var wg sync.WaitGroup
+
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+
+		go func(w int) {
+			defer wg.Done()
+
+			ctx := idx.CreateContext()
+			result := make([]uint32, 10)
+
+			for i := 0; i < 200; i++ {
+				item := uint32((w*200 + i) % 2000)
+				v := idx.GetItem(item)
+
+				res, _ := idx.GetNnsByVector(v, 10, -1, ctx)
+				assert.NotEmpty(t, res)
+
+				res, _ = idx.GetNnsWithinRadius(v, 0.1, -1, ctx)
+				assert.Contains(t, res, item)
+
+				assert.NotZero(t, idx.GetNnsByVectorInto(v, -1, result, nil, ctx))
+				assert.False(t, idx.IsDeleted(item))
+				assert.Zero(t, idx.GetDistance(item, item))
+			}
+
+			results, _ := idx.GetNnsByVectors([][]float32{idx.GetItem(1), idx.GetItem(2)}, 5, -1, 2)
+			assert.Len(t, results, 2)
+		}(w)
+	}
+
+	wg.Wait()
+}
+
+func TestConcurrentSearchesLoadsAndCloses(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{filepath.Join(dir, "small.ann"), filepath.Join(dir, "large.ann")}
+
+	for i, numItems := range []int{100, 1000} {
+		idx := builder.Index[float32, uint32]().EuclideanDistance(4).Build()
+		addRandomItems(idx, numItems, 4)
+		idx.Build(3, -1)
+		require.NoError(t, idx.Save(files[i]))
+		idx.Close()
+	}
+
+	idx := builder.Index[float32, uint32]().EuclideanDistance(4).Build()
+	defer idx.Close()
+
+	require.NoError(t, idx.Load(files[0]))
+
+	var (
+		wg       sync.WaitGroup
+		stop     atomic.Bool
+		searches atomic.Int64
+	)
+
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			// The context is created before the larger index is loaded
+			ctx := idx.CreateContext()
+
+			for !stop.Load() {
+				res, distances := idx.GetNnsByItem(5, 10, -1, ctx)
+				assert.Len(t, distances, len(res))
+
+				idx.GetNnsWithinRadius([]float32{0, 0, 0, 0}, 0.5, -1, ctx)
+				idx.GetNumberOfItems()
+
+				searches.Add(1)
+			}
+		}()
+	}
+
+	// Keep on until the searches have been running alongside the loads for a while
+	for i := 0; i < 50 || searches.Load() < 100; i++ {
+		if i%10 == 9 {
+			require.NoError(t, idx.Close())
+		} else {
+			require.NoError(t, idx.Load(files[i%2]))
+		}
+	}
+
+	stop.Store(true)
+	wg.Wait()
+
+	assert.NotZero(t, searches.Load())
+}
+
+func TestConcurrentAllocatorOpenAndClose(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "alloc.bin")
+	require.NoError(t, os.WriteFile(fileName, make([]byte, 4096), 0o644))
+
+	alloc := memory.MmapIndexAllocator()
+
+	var wg sync.WaitGroup
+
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := 0; i < 100; i++ {
+				mem, err := alloc.Open(fileName)
+				if !assert.NoError(t, err) {
+					return
+				}
+
+				alloc.Get(fileName)
+				assert.NoError(t, mem.Close())
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	_, ok := alloc.Get(fileName)
+	assert.False(t, ok)
+}
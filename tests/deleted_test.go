@@ -0,0 +1,157 @@
+package tests
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mariotoffia/goannoy/builder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarkDeletedIsPersistedAndSkipped(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "deleted.ann")
+
+	idx := builder.Index[float32, uint32]().EuclideanDistance(2).Build()
+	defer idx.Close()
+
+	for i := 0; i < 100; i++ {
+		idx.AddItem(uint32(i), []float32{float32(i), 0})
+	}
+
+	idx.Build(3, -1)
+
+	require.NoError(t, idx.MarkDeleted(50))
+	assert.True(t, idx.IsDeleted(50))
+	assert.Equal(t, 1, idx.DeletedCount())
+	assert.Error(t, idx.MarkDeleted(100))
+
+	require.NoError(t, idx.Save(fileName))
+	assert.FileExists(t, fileName+".del")
+
+	// Loaded index reads the sidecar and writes it at once when marking more items
+	loaded := builder.Index[float32, uint32]().EuclideanDistance(2).Build()
+	defer loaded.Close()
+
+	require.NoError(t, loaded.Load(fileName))
+	assert.True(t, loaded.IsDeleted(50))
+
+	require.NoError(t, loaded.MarkDeleted(51))
+
+	reloaded := builder.Index[float32, uint32]().EuclideanDistance(2).Build()
+	defer reloaded.Close()
+
+	require.NoError(t, reloaded.Load(fileName))
+	assert.Equal(t, 2, reloaded.DeletedCount())
+
+	ctx := reloaded.CreateContext()
+
+	result, _ := reloaded.GetNnsWithinRadius([]float32{50, 0}, 2, -1, ctx)
+	assert.Equal(t, []uint32{49, 48, 52}, result)
+
+	result, _ = reloaded.GetNnsByVector([]float32{50, 0}, 100, 1000, ctx)
+	assert.Len(t, result, 98)
+	assert.NotContains(t, result, uint32(50))
+	assert.NotContains(t, result, uint32(51))
+
+	// Compact purges the deleted items from the trees and writes them back to the file
+	require.NoError(t, reloaded.Compact())
+	assert.Equal(t, 0, reloaded.DeletedCount())
+	assert.Equal(t, 3, reloaded.GetNumberOfTrees())
+
+	_, err := os.Stat(fileName + ".del")
+	assert.True(t, os.IsNotExist(err), "sidecar should be removed: %v", err)
+
+	compacted := builder.Index[float32, uint32]().EuclideanDistance(2).Build()
+	defer compacted.Close()
+
+	require.NoError(t, compacted.Load(fileName))
+	assert.Equal(t, 0, compacted.DeletedCount())
+
+	result, _ = compacted.GetNnsByVector([]float32{50, 0}, 100, 1000, compacted.CreateContext())
+	assert.Len(t, result, 98)
+	assert.NotContains(t, result, uint32(50))
+
+	result, _ = reloaded.GetNnsByVector([]float32{50, 0}, 100, 1000, reloaded.CreateContext())
+	assert.Len(t, result, 98)
+	assert.NotContains(t, result, uint32(50))
+
+	require.NoError(t, reloaded.Save(fileName))
+
+	_, err = os.Stat(fileName + ".del")
+	assert.True(t, os.IsNotExist(err), "sidecar should be removed: %v", err)
+}
+
+func TestSearchWithDeletedItemsAndNonDefaultNodesToInspect(t *testing.T) {
+	idx := builder.Index[float32, uint32]().EuclideanDistance(2).Build()
+	defer idx.Close()
+
+	for i := 0; i < 100; i++ {
+		idx.AddItem(uint32(i), []float32{float32(i), 0})
+	}
+
+	idx.Build(3, -1)
+
+	require.NoError(t, idx.MarkDeleted(50))
+
+	ctx := idx.CreateContext()
+
+	for _, numNodesToInspect := range []int{-2, 0, 1, 10} {
+		result, _ := idx.GetNnsByVector([]float32{50, 0}, 5, numNodesToInspect, ctx)
+
+		assert.NotEmpty(t, result, "numNodesToInspect: %d", numNodesToInspect)
+		assert.NotContains(t, result, uint32(50), "numNodesToInspect: %d", numNodesToInspect)
+	}
+}
+
+func TestCorruptDeletedSidecarDoNotAllocate(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "deleted.ann")
+
+	idx := builder.Index[float32, uint32]().EuclideanDistance(2).Build()
+	defer idx.Close()
+
+	for i := 0; i < 100; i++ {
+		idx.AddItem(uint32(i), []float32{float32(i), 0})
+	}
+
+	idx.Build(3, -1)
+	require.NoError(t, idx.Save(fileName))
+
+	// Claims to have 2^60 words but only holds a single word
+	corrupt := binary.LittleEndian.AppendUint64(nil, 1<<60)
+	corrupt = binary.LittleEndian.AppendUint64(corrupt, 1)
+	require.NoError(t, os.WriteFile(fileName+".del", corrupt, 0o644))
+
+	loaded := builder.Index[float32, uint32]().EuclideanDistance(2).Build()
+	defer loaded.Close()
+
+	assert.Error(t, loaded.Load(fileName))
+}
+
+func TestFailedSaveDoNotWriteDeletedSidecar(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "deleted.ann")
+
+	// A directory in place of the index file makes the write fail
+	require.NoError(t, os.Mkdir(fileName, 0o755))
+
+	idx := builder.Index[float32, uint32]().EuclideanDistance(2).Build()
+	defer idx.Close()
+
+	for i := 0; i < 10; i++ {
+		idx.AddItem(uint32(i), []float32{float32(i), 0})
+	}
+
+	idx.Build(1, -1)
+
+	// All or none of the items are marked
+	assert.Error(t, idx.MarkDeleted(3, 10))
+	assert.Equal(t, 0, idx.DeletedCount())
+
+	require.NoError(t, idx.MarkDeleted(3, 4))
+	assert.Equal(t, 2, idx.DeletedCount())
+
+	assert.Error(t, idx.Save(fileName))
+	assert.NoFileExists(t, fileName+".del")
+}
@@ -0,0 +1,55 @@
+package tests
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/mariotoffia/goannoy/builder"
+	"github.com/mariotoffia/goannoy/index"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddItemErrors(t *testing.T) {
+	idx := builder.Index[float32, uint32]().AngularDistance(3).Build()
+	defer idx.Close()
+
+	err := idx.AddItemE(0, []float32{0, 1})
+	assert.True(t, errors.Is(err, index.ErrDimensionMismatch), "%v", err)
+
+	require.NoError(t, idx.AddItemE(0, []float32{0, 0, 1}))
+	require.NoError(t, idx.AddItemE(1, []float32{0, 1, 0}))
+	require.NoError(t, idx.BuildE(10, -1))
+
+	err = idx.AddItemE(2, []float32{1, 0, 0})
+	assert.True(t, errors.Is(err, index.ErrAlreadyBuilt), "%v", err)
+
+	require.NoError(t, idx.Save(filepath.Join(t.TempDir(), "errors.ann")))
+
+	err = idx.AddItemE(2, []float32{1, 0, 0})
+	assert.True(t, errors.Is(err, index.ErrIndexLoaded), "%v", err)
+
+	assert.Panics(t, func() { idx.AddItem(2, []float32{1, 0, 0}) })
+}
+
+func TestBuildErrors(t *testing.T) {
+	idx := builder.Index[float32, uint32]().AngularDistance(3).Build()
+	defer idx.Close()
+
+	err := idx.Save(filepath.Join(t.TempDir(), "errors.ann"))
+	assert.True(t, errors.Is(err, index.ErrNotBuilt), "%v", err)
+
+	idx.AddItem(0, []float32{0, 0, 1})
+	require.NoError(t, idx.BuildE(10, -1))
+
+	err = idx.BuildE(10, -1)
+	assert.True(t, errors.Is(err, index.ErrAlreadyBuilt), "%v", err)
+
+	require.NoError(t, idx.Save(filepath.Join(t.TempDir(), "errors.ann")))
+
+	err = idx.BuildE(10, -1)
+	assert.True(t, errors.Is(err, index.ErrIndexLoaded), "%v", err)
+
+	assert.Panics(t, func() { idx.Build(10, -1) })
+}
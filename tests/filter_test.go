@@ -0,0 +1,147 @@
+package tests
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mariotoffia/goannoy/builder"
+	"github.com/mariotoffia/goannoy/interfaces"
+	"github.com/mariotoffia/goannoy/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilteredSearchOnlyReturnsMatchingItems(t *testing.T) {
+	idx := builder.Index[float32, uint32]().AngularDistance(8).Build()
+	defer idx.Close()
+
+	addRandomItems(idx, 1000, 8)
+	idx.Build(10, -1)
+
+	ctx := idx.CreateContext()
+
+	// Only every 100th item passes, far less than the default number of nodes to inspect yields
+	result, distances := idx.GetNnsByVectorWithOptions(
+		idx.GetItem(0), 5, -1, ctx,
+		interfaces.SearchOptions[uint32]{
+			Filter: func(item uint32) bool { return item%100 == 0 },
+		},
+	)
+
+	require.Len(t, result, 5)
+	require.Len(t, distances, 5)
+
+	for _, item := range result {
+		assert.Zero(t, item%100, "item %d should be filtered out", item)
+	}
+}
+
+func TestFilteredSearchWithBitset(t *testing.T) {
+	idx := builder.Index[float32, uint32]().EuclideanDistance(2).Build()
+	defer idx.Close()
+
+	for i := 0; i < 100; i++ {
+		idx.AddItem(uint32(i), []float32{float32(i), 0})
+	}
+
+	idx.Build(5, -1)
+
+	allowed := utils.NewBitset[uint32](100)
+	allowed.Set(3)
+	allowed.Set(50)
+	allowed.Set(97)
+
+	result, _ := idx.GetNnsByItemWithOptions(
+		49, 10, -1, idx.CreateContext(),
+		interfaces.SearchOptions[uint32]{Filter: allowed.Contains},
+	)
+
+	// All matching items are returned even if fewer than requested
+	assert.ElementsMatch(t, []uint32{3, 50, 97}, result)
+	assert.Equal(t, uint32(50), result[0])
+}
+
+func TestFilteredSearchWithNonPositiveNodesToInspect(t *testing.T) {
+	idx := builder.Index[float32, uint32]().EuclideanDistance(2).Build()
+	defer idx.Close()
+
+	for i := 0; i < 100; i++ {
+		idx.AddItem(uint32(i), []float32{float32(i), 0})
+	}
+
+	idx.Build(5, -1)
+
+	ctx := idx.CreateContext()
+
+	for _, numNodesToInspect := range []int{0, -2} {
+		result, _ := idx.GetNnsByVectorWithOptions(
+			[]float32{10, 0}, 3, numNodesToInspect, ctx,
+			interfaces.SearchOptions[uint32]{Filter: func(item uint32) bool { return item%10 == 0 }},
+		)
+
+		assert.NotEmpty(t, result, "numNodesToInspect: %d", numNodesToInspect)
+
+		for _, item := range result {
+			assert.Zero(t, item%10, "item %d should be filtered out", item)
+		}
+	}
+}
+
+func TestFilteredSearchStopsWhenNothingMatches(t *testing.T) {
+	idx := builder.Index[float32, uint32]().EuclideanDistance(2).Build()
+	defer idx.Close()
+
+	for i := 0; i < 100; i++ {
+		idx.AddItem(uint32(i), []float32{float32(i), 0})
+	}
+
+	idx.Build(5, -1)
+
+	// The queue is exhausted without any item passing the filter
+	result, _ := idx.GetNnsByVectorWithOptions(
+		[]float32{10, 0}, 3, 1, idx.CreateContext(),
+		interfaces.SearchOptions[uint32]{Filter: func(uint32) bool { return false }},
+	)
+
+	assert.Empty(t, result)
+}
+
+// buildSkewedIndex builds an index where items are direct children of split nodes in many
+// trees, hence an item is collected more than once when inspecting all trees.
+func buildSkewedIndex() interfaces.AnnoyIndex[float32, uint32] {
+	idx := builder.Index[float32, uint32]().EuclideanDistance(1).Build()
+
+	for i := 0; i < 200; i++ {
+		idx.AddItem(uint32(i), []float32{float32(math.Pow(3, float64(i%30))) + float32(i)})
+	}
+
+	idx.Build(50, -1)
+
+	return idx
+}
+
+func TestSearchWithBudgetLargerThanContext(t *testing.T) {
+	idx := buildSkewedIndex()
+	defer idx.Close()
+
+	ctx := idx.CreateContext()
+	query := idx.GetItem(3)
+
+	result, _ := idx.GetNnsByVectorWithOptions(
+		query, 5, -1, ctx,
+		interfaces.SearchOptions[uint32]{Filter: func(item uint32) bool { return item != 0 }},
+	)
+
+	assert.NotEmpty(t, result)
+	assert.NotContains(t, result, uint32(0))
+
+	result, _ = idx.GetNnsByVectorWithOptions(
+		query, 200, 1<<30, ctx,
+		interfaces.SearchOptions[uint32]{Filter: func(item uint32) bool { return item%2 == 0 }},
+	)
+
+	assert.Len(t, result, 100)
+
+	result, _ = idx.GetNnsByVector(query, 3, 1<<30, ctx)
+	assert.Len(t, result, 3)
+}
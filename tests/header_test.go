@@ -0,0 +1,111 @@
+package tests
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mariotoffia/goannoy/builder"
+	"github.com/mariotoffia/goannoy/index"
+	"github.com/mariotoffia/goannoy/interfaces"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createHeaderIndex(t *testing.T, fileName string) {
+	idx := builder.Index[float32, uint32]().
+		AngularDistance(3).
+		FileHeader().
+		Build()
+
+	defer idx.Close()
+
+	idx.AddItem(0, []float32{0, 0, 1})
+	idx.AddItem(1, []float32{0, 1, 0})
+	idx.AddItem(2, []float32{1, 0, 0})
+	idx.Build(10, -1)
+
+	require.NoError(t, idx.Save(fileName))
+}
+
+func TestHeaderIsValidatedOnLoad(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "header.ann")
+	createHeaderIndex(t, fileName)
+
+	idx := builder.Index[float32, uint32]().
+		AngularDistance(3).
+		Build()
+
+	defer idx.Close()
+
+	require.NoError(t, idx.Load(fileName))
+
+	result, _ := idx.GetNnsByVector([]float32{3, 2, 1}, 3, -1, idx.CreateContext())
+	assert.Equal(t, []uint32{2, 1, 0}, result)
+	assert.Equal(t, []float32{0, 1, 0}, idx.GetItem(1))
+}
+
+func TestHeaderRejectsIncompatibleIndex(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "header.ann")
+	createHeaderIndex(t, fileName)
+
+	tests := map[string]interfaces.AnnoyIndex[float32, uint32]{
+		"vector length": builder.Index[float32, uint32]().AngularDistance(4).Build(),
+		"metric":        builder.Index[float32, uint32]().EuclideanDistance(3).Build(),
+	}
+
+	for name, idx := range tests {
+		err := idx.Load(fileName)
+		assert.True(t, errors.Is(err, index.ErrIncompatibleIndex), "%s: %v", name, err)
+		idx.Close()
+	}
+
+	idx64 := builder.Index[float64, uint32]().AngularDistance(3).Build()
+	defer idx64.Close()
+
+	err := idx64.Load(fileName)
+	assert.True(t, errors.Is(err, index.ErrIncompatibleIndex), "element size: %v", err)
+}
+
+func TestHeaderDetectsCorruption(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "header.ann")
+	createHeaderIndex(t, fileName)
+
+	data, err := os.ReadFile(fileName)
+	require.NoError(t, err)
+
+	data[len(data)-1] ^= 0xFF
+	require.NoError(t, os.WriteFile(fileName, data, 0644))
+
+	idx := builder.Index[float32, uint32]().AngularDistance(3).Build()
+	defer idx.Close()
+
+	err = idx.Load(fileName)
+	assert.True(t, errors.Is(err, index.ErrInvalidHeader), "%v", err)
+}
+
+func TestLoadWithoutHeader(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "plain.ann")
+
+	idx := builder.Index[float32, uint32]().AngularDistance(3).Build()
+	defer idx.Close()
+
+	idx.AddItem(0, []float32{0, 0, 1})
+	idx.AddItem(1, []float32{0, 1, 0})
+	idx.AddItem(2, []float32{1, 0, 0})
+	idx.Build(10, -1)
+
+	require.NoError(t, idx.Save(fileName))
+
+	stat, err := os.Stat(fileName)
+	require.NoError(t, err)
+
+	// 3 items, 10 roots and 10 root copies, each node is 24 bytes
+	assert.Equal(t, int64(23*24), stat.Size())
+
+	require.NoError(t, idx.Load(fileName))
+
+	result, _ := idx.GetNnsByVector([]float32{1, 2, 3}, 3, -1, idx.CreateContext())
+	assert.Equal(t, []uint32{0, 1, 2}, result)
+}
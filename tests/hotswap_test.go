@@ -0,0 +1,175 @@
+package tests
+
+import (
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mariotoffia/goannoy/builder"
+	"github.com/mariotoffia/goannoy/hotswap"
+	"github.com/mariotoffia/goannoy/interfaces"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// closeTracker records when the wrapped index is closed.
+type closeTracker struct {
+	interfaces.AnnoyIndex[float32, uint32]
+	closed atomic.Bool
+}
+
+func (ct *closeTracker) Close() error {
+	ct.closed.Store(true)
+	return ct.AnnoyIndex.Close()
+}
+
+func saveLineIndex(t *testing.T, fileName string, numItems int) {
+	idx := builder.Index[float32, uint32]().EuclideanDistance(1).Build()
+	defer idx.Close()
+
+	for i := 0; i < numItems; i++ {
+		idx.AddItem(uint32(i), []float32{float32(i)})
+	}
+
+	idx.Build(3, -1)
+	require.NoError(t, idx.Save(fileName))
+}
+
+func newHotswapHandle() (*hotswap.Handle[float32, uint32], *[]*closeTracker) {
+	var (
+		lock     sync.Mutex
+		trackers []*closeTracker
+	)
+
+	return hotswap.New(func() interfaces.AnnoyIndex[float32, uint32] {
+		ct := &closeTracker{AnnoyIndex: builder.Index[float32, uint32]().EuclideanDistance(1).Build()}
+
+		lock.Lock()
+		trackers = append(trackers, ct)
+		lock.Unlock()
+
+		return ct
+	}), &trackers
+}
+
+func TestHotswapClosesOldIndexAfterLeasesAreReleased(t *testing.T) {
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "first.ann"), filepath.Join(dir, "second.ann")
+
+	saveLineIndex(t, first, 100)
+	saveLineIndex(t, second, 200)
+
+	h, trackers := newHotswapHandle()
+
+	assert.Nil(t, h.Acquire())
+
+	require.NoError(t, h.Reload(first))
+
+	lease := h.Acquire()
+	require.NotNil(t, lease)
+
+	require.NoError(t, h.Reload(second))
+
+	// The old index is still in use by the lease
+	assert.False(t, (*trackers)[0].closed.Load())
+	assert.Equal(t, uint32(100), lease.Index().GetNumberOfItems())
+	assert.Equal(t, []float32{42}, lease.Index().GetItem(42))
+
+	result, _ := lease.Index().GetNnsByVector([]float32{42}, 1, -1, lease.Context())
+	assert.Len(t, result, 1)
+
+	require.NoError(t, lease.Release())
+	assert.True(t, (*trackers)[0].closed.Load())
+
+	// New searches use the new index
+	lease = h.Acquire()
+	assert.Equal(t, uint32(200), lease.Index().GetNumberOfItems())
+	require.NoError(t, lease.Release())
+
+	// A failed reload keeps the current index
+	assert.Error(t, h.Reload(filepath.Join(dir, "missing.ann")))
+	assert.False(t, (*trackers)[1].closed.Load())
+
+	result, _ = h.GetNnsByVector([]float32{150}, 1, -1)
+	assert.Len(t, result, 1)
+
+	require.NoError(t, h.Close())
+	assert.True(t, (*trackers)[1].closed.Load())
+	assert.Nil(t, h.Acquire())
+	assert.ErrorIs(t, h.Reload(second), hotswap.ErrClosed)
+}
+
+func TestHotswapReleaseIsIdempotent(t *testing.T) {
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "first.ann"), filepath.Join(dir, "second.ann")
+
+	saveLineIndex(t, first, 100)
+	saveLineIndex(t, second, 200)
+
+	h, trackers := newHotswapHandle()
+	defer h.Close()
+
+	require.NoError(t, h.Reload(first))
+
+	lease, other := h.Acquire(), h.Acquire()
+
+	require.NoError(t, h.Reload(second))
+
+	// Releasing twice must not drop the reference held by the other lease
+	require.NoError(t, lease.Release())
+	require.NoError(t, lease.Release())
+	assert.False(t, (*trackers)[0].closed.Load())
+	assert.Equal(t, []float32{42}, other.Index().GetItem(42))
+
+	require.NoError(t, other.Release())
+	assert.True(t, (*trackers)[0].closed.Load())
+}
+
+func TestHotswapReloadWhileSearching(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{filepath.Join(dir, "a.ann"), filepath.Join(dir, "b.ann")}
+
+	saveLineIndex(t, files[0], 100)
+	saveLineIndex(t, files[1], 200)
+
+	h, trackers := newHotswapHandle()
+	require.NoError(t, h.Reload(files[0]))
+
+	var (
+		wg   sync.WaitGroup
+		stop atomic.Bool
+	)
+
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for !stop.Load() {
+				lease := h.Acquire()
+				n := lease.Index().GetNumberOfItems()
+
+				_, distances := lease.Index().GetNnsByVector([]float32{50}, 5, -1, lease.Context())
+
+				assert.Contains(t, []uint32{100, 200}, n)
+				assert.NotEmpty(t, distances)
+				assert.NoError(t, lease.Release())
+			}
+		}()
+	}
+
+	for i := 0; i < 20; i++ {
+		require.NoError(t, h.Reload(files[i%2]))
+	}
+
+	stop.Store(true)
+	wg.Wait()
+
+	require.NoError(t, h.Close())
+
+	for _, ct := range *trackers {
+		assert.True(t, ct.closed.Load())
+	}
+}
@@ -0,0 +1,118 @@
+package tests
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/mariotoffia/goannoy/builder"
+	"github.com/mariotoffia/goannoy/index"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIncrementalInsertsAfterBuild(t *testing.T) {
+	idx := builder.Index[float32, uint32]().EuclideanDistance(2).Incremental().Build()
+	defer idx.Close()
+
+	for i := 0; i < 100; i++ {
+		idx.AddItem(uint32(i), []float32{float32(i), 0})
+	}
+
+	idx.Build(3, -1)
+
+	ctx := idx.CreateContext()
+
+	// New item and a replaced item, both searched by brute force
+	require.NoError(t, idx.AddItemE(100, []float32{50.2, 0}))
+	require.NoError(t, idx.AddItemE(10, []float32{49.9, 0}))
+
+	assert.Equal(t, uint32(101), idx.GetNumberOfItems())
+	assert.Equal(t, []float32{49.9, 0}, idx.GetItem(10))
+
+	// Distances are between the delta items and not the old positions
+	assert.InDelta(t, float32(0.3), idx.GetDistance(10, 100), 1e-5)
+	assert.InDelta(t, float32(0.2), idx.GetDistance(50, 100), 1e-5)
+	assert.InDelta(t, float32(0.01), idx.GetRawDistance(10, 50), 1e-5)
+
+	result, distances := idx.GetNnsWithinRadius([]float32{50, 0}, 0.5, -1, ctx)
+	assert.Equal(t, []uint32{50, 10, 100}, result)
+	assert.InDeltaSlice(t, []float32{0, 0.1, 0.2}, distances, 1e-5)
+
+	// Item 10 has been moved and is no longer found at its old position
+	result, _ = idx.GetNnsWithinRadius([]float32{10, 0}, 0.5, -1, ctx)
+	assert.Empty(t, result)
+
+	err := idx.Save(filepath.Join(t.TempDir(), "incremental.ann"))
+	assert.True(t, errors.Is(err, index.ErrNotCompacted), "%v", err)
+
+	require.NoError(t, idx.Compact())
+	assert.Equal(t, 3, idx.GetNumberOfTrees())
+	assert.Equal(t, uint32(101), idx.GetNumberOfItems())
+
+	result, distances = idx.GetNnsWithinRadius([]float32{50, 0}, 0.5, -1, idx.CreateContext())
+	assert.Equal(t, []uint32{50, 10, 100}, result)
+	assert.InDeltaSlice(t, []float32{0, 0.1, 0.2}, distances, 1e-5)
+}
+
+func TestIncrementalInsertsIntoLoadedIndex(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "incremental.ann")
+
+	idx := builder.Index[float32, uint32]().AngularDistance(8).Incremental().Build()
+	defer idx.Close()
+
+	addRandomItems(idx, 200, 8)
+	idx.Build(5, -1)
+	require.NoError(t, idx.Save(fileName))
+
+	// The saved index is loaded, add the first item again as a new item
+	query := append([]float32(nil), idx.GetItem(0)...)
+	require.NoError(t, idx.AddItemE(200, query))
+
+	result, _ := idx.GetNnsByVector(query, 2, -1, idx.CreateContext())
+	assert.Contains(t, result, uint32(200))
+
+	require.NoError(t, idx.Compact())
+	require.NoError(t, idx.Save(fileName))
+
+	loaded := builder.Index[float32, uint32]().AngularDistance(8).Build()
+	defer loaded.Close()
+
+	require.NoError(t, loaded.Load(fileName))
+	assert.Equal(t, uint32(201), loaded.GetNumberOfItems())
+	assert.Equal(t, 5, loaded.GetNumberOfTrees())
+	assert.Equal(t, query, loaded.GetItem(200))
+}
+
+func TestCompactRequiresIncrementalMode(t *testing.T) {
+	idx := builder.Index[float32, uint32]().EuclideanDistance(2).Build()
+	defer idx.Close()
+
+	idx.AddItem(0, []float32{0, 0})
+	idx.Build(1, -1)
+
+	assert.Error(t, idx.Compact())
+	assert.True(t, errors.Is(idx.AddItemE(1, []float32{1, 1}), index.ErrAlreadyBuilt))
+}
+
+func TestCompactKeepsDeletedItemsAddedAgain(t *testing.T) {
+	idx := builder.Index[float32, uint32]().EuclideanDistance(2).Incremental().Build()
+	defer idx.Close()
+
+	for i := 0; i < 100; i++ {
+		idx.AddItem(uint32(i), []float32{float32(i), 0})
+	}
+
+	idx.Build(3, 2)
+
+	idx.MarkDeleted(10)
+	require.NoError(t, idx.AddItemE(10, []float32{49.9, 0}))
+	assert.False(t, idx.IsDeleted(10))
+
+	require.NoError(t, idx.Compact())
+	assert.False(t, idx.IsDeleted(10))
+	assert.Equal(t, []float32{49.9, 0}, idx.GetItem(10))
+
+	result, _ := idx.GetNnsWithinRadius([]float32{50, 0}, 0.15, -1, idx.CreateContext())
+	assert.Equal(t, []uint32{50, 10}, result)
+}
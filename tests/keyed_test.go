@@ -0,0 +1,137 @@
+package tests
+
+import (
+	"encoding/gob"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/mariotoffia/goannoy/builder"
+	"github.com/mariotoffia/goannoy/keyed"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyedIndexSaveAndLoad(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "keyed.ann")
+
+	ki := keyed.New[string](builder.Index[float32, uint32]().EuclideanDistance(2).Build())
+	defer ki.Close()
+
+	require.NoError(t, ki.AddKey("a3f1c2", []float32{0, 0}))
+	require.NoError(t, ki.AddKey("b77e01", []float32{1, 0}))
+	require.NoError(t, ki.AddKey("c0ffee", []float32{5, 0}))
+	require.NoError(t, ki.AddKey("a3f1c2", []float32{4, 0})) // replaces
+
+	id, ok := ki.ID("c0ffee")
+	assert.True(t, ok)
+	assert.Equal(t, uint32(2), id)
+
+	require.NoError(t, ki.Build(3, -1))
+	require.NoError(t, ki.Save(fileName))
+	assert.FileExists(t, fileName+".keys")
+
+	loaded := keyed.New[string](builder.Index[float32, uint32]().EuclideanDistance(2).Build())
+	defer loaded.Close()
+
+	require.NoError(t, loaded.Load(fileName))
+
+	result, distances, err := loaded.GetNnsByKey("c0ffee", 3, -1, loaded.CreateContext())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c0ffee", "a3f1c2", "b77e01"}, result)
+	assert.Equal(t, []float32{0, 1, 4}, distances)
+
+	v, ok := loaded.GetItemByKey("a3f1c2")
+	assert.True(t, ok)
+	assert.Equal(t, []float32{4, 0}, v)
+
+	_, _, err = loaded.GetNnsByKey("missing", 3, -1, loaded.CreateContext())
+	assert.Error(t, err)
+}
+
+func TestKeyedIndexWithSparseIds(t *testing.T) {
+	ki := keyed.New[int64](builder.Index[float32, uint32]().EuclideanDistance(1).Build())
+	defer ki.Close()
+
+	for _, key := range []int64{9000000001, -7, 42} {
+		require.NoError(t, ki.AddKey(key, []float32{float32(key % 100)}))
+	}
+
+	require.NoError(t, ki.Build(1, -1))
+
+	result, _ := ki.GetNnsByVector([]float32{40}, 1, -1, ki.CreateContext())
+	assert.Equal(t, []int64{42}, result)
+
+	require.NoError(t, ki.DeleteKey(42))
+
+	result, _ = ki.GetNnsByVector([]float32{40}, 1, -1, ki.CreateContext())
+	assert.Equal(t, []int64{9000000001}, result)
+}
+
+func TestKeyedIndexLoadWithMismatchingKeys(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "keyed.ann")
+
+	ki := keyed.New[string](builder.Index[float32, uint32]().EuclideanDistance(2).Build())
+	defer ki.Close()
+
+	require.NoError(t, ki.AddKey("a", []float32{0, 0}))
+	require.NoError(t, ki.AddKey("b", []float32{1, 0}))
+	require.NoError(t, ki.AddKey("c", []float32{2, 0}))
+	require.NoError(t, ki.Build(1, -1))
+	require.NoError(t, ki.Save(fileName))
+
+	file, err := os.Create(fileName + ".keys")
+	require.NoError(t, err)
+	require.NoError(t, gob.NewEncoder(file).Encode([]string{"a"}))
+	require.NoError(t, file.Close())
+
+	loaded := keyed.New[string](builder.Index[float32, uint32]().EuclideanDistance(2).Build())
+	defer loaded.Close()
+
+	assert.Error(t, loaded.Load(fileName))
+
+	// Items without a key are not returned
+	result, distances := loaded.GetNnsByVector([]float32{0, 0}, 3, -1, loaded.CreateContext())
+	assert.Empty(t, result)
+	assert.Empty(t, distances)
+}
+
+func TestKeyedIndexConcurrentAddAndSearch(t *testing.T) {
+	ki := keyed.New[string](
+		builder.Index[float32, uint32]().EuclideanDistance(2).Incremental().Build(),
+	)
+	defer ki.Close()
+
+	for i := 0; i < 100; i++ {
+		require.NoError(t, ki.AddKey(fmt.Sprint("key-", i), []float32{float32(i), 0}))
+	}
+
+	require.NoError(t, ki.Build(3, -1))
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		for i := 100; i < 200; i++ {
+			assert.NoError(t, ki.AddKey(fmt.Sprint("key-", i), []float32{float32(i), 0}))
+		}
+	}()
+
+	ctx := ki.CreateContext()
+
+	for i := 0; i < 100; i++ {
+		result, distances, err := ki.GetNnsByKey(fmt.Sprint("key-", i), 5, -1, ctx)
+		require.NoError(t, err)
+		assert.Len(t, result, len(distances))
+	}
+
+	wg.Wait()
+
+	result, _ := ki.GetNnsByVector([]float32{199, 0}, 1, -1, ki.CreateContext())
+	assert.Equal(t, []string{"key-199"}, result)
+}
@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/mariotoffia/goannoy/builder"
+	"github.com/mariotoffia/goannoy/index/sharded"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//go:embed testdata/*.ann
+var fixtures embed.FS
+
+func TestLoadFSFromEmbeddedFixtures(t *testing.T) {
+	for _, metric := range []string{"angular", "euclidean", "dot"} {
+		t.Run(metric, func(t *testing.T) {
+			idx := annoyCompatibleIndex(metric)
+			defer idx.Close()
+
+			require.NoError(t, idx.LoadFS(fixtures, "testdata/"+metric+"_f2_n3.ann"))
+
+			assert.Equal(t, uint32(3), idx.GetNumberOfItems())
+
+			for i, v := range annoyFixtureItems {
+				assert.Equal(t, v, idx.GetItem(uint32(i)))
+			}
+		})
+	}
+}
+
+func TestLoadBytesAndReaderAt(t *testing.T) {
+	data, err := fixtures.ReadFile("testdata/euclidean_f2_n3.ann")
+	require.NoError(t, err)
+
+	// Misalign the data to force a copy
+	unaligned := make([]byte, len(data)+1)[1:]
+	copy(unaligned, data)
+
+	idx := annoyCompatibleIndex("euclidean")
+	defer idx.Close()
+
+	require.NoError(t, idx.LoadBytes(unaligned))
+	assert.Equal(t, annoyFixtureItems[2], idx.GetItem(2))
+
+	result, _ := idx.GetNnsByVector([]float32{2, 0}, 3, -1, idx.CreateContext())
+	assert.Equal(t, []uint32{0, 1, 2}, result)
+
+	require.NoError(t, idx.LoadReaderAt(bytes.NewReader(data), int64(len(data))))
+	assert.Equal(t, annoyFixtureItems[1], idx.GetItem(1))
+
+	assert.Error(t, idx.LoadReaderAt(bytes.NewReader(data), int64(len(data)+10)))
+	assert.Error(t, idx.LoadBytes(nil))
+}
+
+func TestLoadFSWithSidecars(t *testing.T) {
+	dir := t.TempDir()
+	fileName := filepath.Join(dir, "deleted.ann")
+
+	idx := builder.Index[float32, uint32]().EuclideanDistance(2).Build()
+	defer idx.Close()
+
+	for i := 0; i < 10; i++ {
+		idx.AddItem(uint32(i), []float32{float32(i), 0})
+	}
+
+	idx.Build(2, -1)
+	require.NoError(t, idx.MarkDeleted(3))
+	require.NoError(t, idx.Save(fileName))
+
+	nodes, err := os.ReadFile(fileName)
+	require.NoError(t, err)
+
+	deleted, err := os.ReadFile(fileName + ".del")
+	require.NoError(t, err)
+
+	fsys := fstest.MapFS{
+		"deleted.ann":     {Data: nodes},
+		"deleted.ann.del": {Data: deleted},
+	}
+
+	loaded := builder.Index[float32, uint32]().EuclideanDistance(2).Build()
+	defer loaded.Close()
+
+	require.NoError(t, loaded.LoadFS(fsys, "deleted.ann"))
+	assert.True(t, loaded.IsDeleted(3))
+	assert.Equal(t, uint32(10), loaded.GetNumberOfItems())
+
+	// Deletions are not written back to the file system
+	require.NoError(t, loaded.MarkDeleted(4))
+	assert.Equal(t, deleted, fsys["deleted.ann.del"].Data)
+
+	assert.Error(t, loaded.LoadFS(fsys, "missing.ann"))
+}
+
+func TestShardedLoadFS(t *testing.T) {
+	dir := t.TempDir()
+
+	idx := newShardedIndex(2, sharded.HashPartition[uint32]())
+	defer idx.Close()
+
+	ingestRandomItems(t, idx, 200)
+	idx.Build(2, -1)
+	require.NoError(t, idx.Save(filepath.Join(dir, "sharded.ann")))
+
+	loaded := newShardedIndex(2, sharded.HashPartition[uint32]())
+	defer loaded.Close()
+
+	require.NoError(t, loaded.LoadFS(os.DirFS(dir), "sharded.ann"))
+	assert.Equal(t, uint32(200), loaded.GetNumberOfItems())
+	assert.Equal(t, idx.GetItem(17), loaded.GetItem(17))
+
+	assert.ErrorIs(t, loaded.LoadBytes([]byte{1}), errors.ErrUnsupported)
+}
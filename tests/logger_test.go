@@ -0,0 +1,53 @@
+package tests
+
+import (
+	"bytes"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	"github.com/mariotoffia/goannoy/builder"
+	"github.com/mariotoffia/goannoy/index"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoggerOnlyDumpsNodesAtTraceLevel(t *testing.T) {
+	for name, level := range map[string]slog.Level{
+		"info":  slog.LevelInfo,
+		"trace": index.LevelTrace,
+	} {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: level}))
+
+			idx := builder.Index[float32, uint32]().
+				AngularDistance(3).
+				Logger(logger).
+				Build()
+
+			defer idx.Close()
+
+			idx.AddItem(0, []float32{0, 0, 1})
+			idx.AddItem(1, []float32{0, 1, 0})
+			idx.AddItem(2, []float32{1, 0, 0})
+			idx.Build(2, -1)
+
+			require.NoError(t, idx.Save(filepath.Join(t.TempDir(), "logger.ann")))
+
+			out := buf.String()
+
+			assert.Contains(t, out, `msg="index built" items=3`)
+			assert.Contains(t, out, `msg="saving index"`)
+			assert.Contains(t, out, `msg="loaded index"`)
+
+			if level == index.LevelTrace {
+				assert.Contains(t, out, `msg="added item" item=0`)
+			} else {
+				assert.NotContains(t, out, `msg="added item"`)
+				assert.NotContains(t, out, "max nns")
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/mariotoffia/goannoy/builder"
+	"github.com/mariotoffia/goannoy/index/memory"
+	"github.com/mariotoffia/goannoy/interfaces"
+	"github.com/mariotoffia/goannoy/metadata"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetadataFilteredSearch(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "meta.ann")
+
+	idx := builder.Index[float32, uint32]().EuclideanDistance(1).Build()
+	defer idx.Close()
+
+	meta := metadata.New[uint32]("category", "timestamp", "tenant")
+
+	for i := 0; i < 100; i++ {
+		idx.AddItem(uint32(i), []float32{float32(i)})
+		require.NoError(t, meta.Set(uint32(i), int64(i%3), int64(1700000000+i), int64(i%2)))
+	}
+
+	idx.Build(3, -1)
+	require.NoError(t, idx.Save(fileName))
+	require.NoError(t, meta.Save(fileName+metadata.Suffix))
+
+	for name, allocator := range map[string]interfaces.IndexAllocator{
+		"mmap": memory.MmapIndexAllocator(),
+		"gc":   memory.FileIndexMemoryAllocator(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			loaded, err := metadata.Load[uint32](fileName+metadata.Suffix, allocator)
+			require.NoError(t, err)
+
+			defer loaded.Close()
+
+			assert.Equal(t, []string{"category", "timestamp", "tenant"}, loaded.Fields())
+			assert.Equal(t, 100, loaded.Len())
+			assert.Equal(t, int64(1700000042), loaded.Get(42, 1))
+			assert.Error(t, loaded.Set(1, 0, 0, 0))
+
+			filter, err := loaded.Filter(metadata.And(
+				metadata.In("category", 0, 2),
+				metadata.Range("timestamp", 1700000040, 1700000060),
+				metadata.Not(metadata.Eq("tenant", 1)),
+			))
+
+			require.NoError(t, err)
+
+			result, _ := idx.GetNnsWithinRadius([]float32{50}, 100, -1, idx.CreateContext())
+
+			var expected []uint32
+
+			for _, item := range result {
+				if filter(item) {
+					expected = append(expected, item)
+				}
+			}
+
+			// Even items with category 0 or 2 between 40 and 60
+			assert.ElementsMatch(t, []uint32{42, 44, 48, 50, 54, 56, 60}, expected)
+
+			result, _ = idx.GetNnsByVectorWithOptions(
+				[]float32{50}, 3, -1, idx.CreateContext(),
+				interfaces.SearchOptions[uint32]{Filter: filter},
+			)
+
+			assert.Len(t, result, 3)
+
+			for _, item := range result {
+				assert.Contains(t, expected, item)
+			}
+
+			_, err = loaded.Filter(metadata.Eq("missing", 1))
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package tests
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"unsafe"
+
+	"github.com/mariotoffia/goannoy/builder"
+	"github.com/mariotoffia/goannoy/distance/angular"
+	"github.com/mariotoffia/goannoy/index"
+	"github.com/mariotoffia/goannoy/index/memory"
+	"github.com/mariotoffia/goannoy/index/policy"
+	"github.com/mariotoffia/goannoy/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOnDiskBuild(t *testing.T) {
+	const (
+		numItems     = 500
+		vectorLength = 8
+	)
+
+	dir := t.TempDir()
+	fileName := filepath.Join(dir, "ondisk.ann")
+
+	idx := builder.Index[float32, uint32]().
+		AngularDistance(vectorLength).
+		OnDiskBuild(fileName).
+		Build()
+
+	defer idx.Close()
+
+	rnd := random.NewGoRandom()
+	vectors := make([][]float32, numItems)
+
+	for i := range vectors {
+		vectors[i] = make([]float32, vectorLength)
+		for j := range vectors[i] {
+			vectors[i][j] = float32(rnd.NormFloat64())
+		}
+
+		idx.AddItem(uint32(i), vectors[i])
+	}
+
+	require.NoError(t, idx.BuildE(10, -1))
+
+	ctx := idx.CreateContext()
+	expected, _ := idx.GetNnsByItem(7, 10, -1, ctx)
+
+	// Saving to another file must write the nodes held in the on disk build file
+	otherFile := filepath.Join(dir, "other.ann")
+	require.NoError(t, idx.Save(otherFile))
+
+	other, err := os.ReadFile(otherFile)
+	require.NoError(t, err)
+
+	built, err := os.ReadFile(fileName)
+	require.NoError(t, err)
+
+	assert.Equal(t, built, other)
+
+	loaded := builder.Index[float32, uint32]().AngularDistance(vectorLength).Build()
+	defer loaded.Close()
+
+	require.NoError(t, loaded.Load(fileName))
+
+	for i, v := range vectors {
+		assert.Equal(t, v, loaded.GetItem(uint32(i)))
+	}
+
+	result, _ := loaded.GetNnsByItem(7, 10, -1, loaded.CreateContext())
+	assert.Equal(t, expected, result)
+}
+
+func TestOnDiskBuildSaveToSameFileOnlyLoads(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "ondisk.ann")
+
+	idx := builder.Index[float32, uint32]().
+		EuclideanDistance(2).
+		OnDiskBuild(fileName).
+		Build()
+
+	defer idx.Close()
+
+	idx.AddItem(0, []float32{0, 0})
+	idx.AddItem(1, []float32{1, 0})
+	idx.AddItem(2, []float32{2, 0})
+	require.NoError(t, idx.BuildE(1, -1))
+
+	stat, err := os.Stat(fileName)
+	require.NoError(t, err)
+
+	// The file is truncated to 3 items, 1 root and 1 root copy, each node is 24 bytes
+	assert.Equal(t, int64(5*24), stat.Size())
+
+	require.NoError(t, idx.Save(fileName))
+
+	result, _ := idx.GetNnsByVector([]float32{2, 0}, 3, -1, idx.CreateContext())
+	assert.Equal(t, []uint32{2, 1, 0}, result)
+}
+
+func TestOnDiskBuildReturnsAllocationErrors(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "missing", "ondisk.ann")
+
+	idx := builder.Index[float32, uint32]().
+		EuclideanDistance(2).
+		OnDiskBuild(fileName).
+		Build()
+
+	defer idx.Close()
+
+	assert.ErrorIs(t, idx.AddItemE(0, []float32{0, 0}), os.ErrNotExist)
+}
+
+var errOutOfMemory = errors.New("out of memory")
+
+// limitedAllocator fails to allocate more than _limit_ bytes.
+type limitedAllocator struct {
+	*memory.GoGCIndexBuildAllocatorImpl
+	limit int
+}
+
+func (a *limitedAllocator) Reallocate(byteSize int) (unsafe.Pointer, error) {
+	if byteSize > a.limit {
+		return nil, errOutOfMemory
+	}
+
+	return a.GoGCIndexBuildAllocatorImpl.Reallocate(byteSize)
+}
+
+func TestBuildReturnsAllocationErrors(t *testing.T) {
+	const (
+		numItems     = 200
+		vectorLength = 4
+	)
+
+	distance := angular.Distance[float32, uint32](vectorLength)
+
+	idx := index.New[float32, uint32](
+		random.NewKiss32Random(uint32(0)),
+		distance,
+		policy.MultiWorker(),
+		&limitedAllocator{
+			GoGCIndexBuildAllocatorImpl: memory.GoGCIndexAllocator(),
+			limit:                       int(distance.NodeSize()) * numItems * 2,
+		},
+		memory.MmapIndexAllocator(),
+		nil, /*sorter*/
+		false,
+		0,
+	)
+
+	defer idx.Close()
+
+	addRandomItems(idx, numItems, vectorLength)
+
+	assert.ErrorIs(t, idx.BuildE(10, 4), errOutOfMemory)
+}
+
+// failingTruncateAllocator is an in memory `interfaces.FileBuildIndexAllocator` where the first
+// _failures_ truncates fail.
+type failingTruncateAllocator struct {
+	*memory.GoGCIndexBuildAllocatorImpl
+	failures int
+}
+
+func (a *failingTruncateAllocator) FileName() string {
+	return "in-memory"
+}
+
+func (a *failingTruncateAllocator) Truncate(byteSize int) (unsafe.Pointer, error) {
+	if a.failures > 0 {
+		a.failures--
+		return nil, errOutOfMemory
+	}
+
+	return a.GoGCIndexBuildAllocatorImpl.Reallocate(byteSize)
+}
+
+func TestBuildDropsTreesWhenTruncateFails(t *testing.T) {
+	const (
+		numItems     = 200
+		vectorLength = 4
+	)
+
+	idx := index.New[float32, uint32](
+		random.NewKiss32Random(uint32(0)),
+		angular.Distance[float32, uint32](vectorLength),
+		policy.MultiWorker(),
+		&failingTruncateAllocator{
+			GoGCIndexBuildAllocatorImpl: memory.GoGCIndexAllocator(),
+			failures:                    1,
+		},
+		memory.MmapIndexAllocator(),
+		nil, /*sorter*/
+		false,
+		0,
+	)
+
+	defer idx.Close()
+
+	addRandomItems(idx, numItems, vectorLength)
+
+	assert.ErrorIs(t, idx.BuildE(10, 4), errOutOfMemory)
+	assert.Zero(t, idx.GetNumberOfTrees())
+
+	// A retry builds from the items only
+	require.NoError(t, idx.BuildE(10, 4))
+	assert.Equal(t, 10, idx.GetNumberOfTrees())
+
+	result, _ := idx.GetNnsByItem(7, 5, -1, idx.CreateContext())
+	assert.NotEmpty(t, result)
+}
@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"math"
+	"sort"
+	"testing"
+
+	"github.com/mariotoffia/goannoy/builder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetNnsWithinRadiusFindsAllItems(t *testing.T) {
+	for name, bld := range map[string]*builder.AnnoyIndexBuilderImpl[float32, uint32]{
+		"euclidean": builder.Index[float32, uint32]().EuclideanDistance(4),
+		"manhattan": builder.Index[float32, uint32]().ManhattanDistance(4),
+		"angular":   builder.Index[float32, uint32]().AngularDistance(4),
+	} {
+		t.Run(name, func(t *testing.T) {
+			idx := bld.Build()
+			defer idx.Close()
+
+			addRandomItems(idx, 2000, 4)
+			idx.Build(5, -1)
+
+			query := idx.GetItem(7)
+			radius := float32(0.8)
+
+			// Brute force
+			var expected []uint32
+
+			for i := uint32(0); i < 2000; i++ {
+				if idx.GetDistance(7, i) <= radius {
+					expected = append(expected, i)
+				}
+			}
+
+			require.NotEmpty(t, expected)
+
+			result, distances := idx.GetNnsWithinRadius(query, radius, -1, idx.CreateContext())
+
+			assert.ElementsMatch(t, expected, result)
+			assert.True(t, sort.SliceIsSorted(distances, func(i, j int) bool {
+				return distances[i] < distances[j]
+			}))
+
+			for _, d := range distances {
+				assert.LessOrEqual(t, d, radius)
+			}
+		})
+	}
+}
+
+func TestGetNnsWithinRadiusWithRandomizedSplits(t *testing.T) {
+	idx := builder.Index[float32, uint32]().EuclideanDistance(1).Build()
+	defer idx.Close()
+
+	// A dense cluster with a few far away outliers. Split planes separates the outliers,
+	// hence they are too imbalanced and the sides are randomized.
+	for i := 0; i < 10000; i++ {
+		idx.AddItem(uint32(i), []float32{float32(i) * 1e-7})
+	}
+
+	for i := 0; i < 50; i++ {
+		idx.AddItem(uint32(10000+i), []float32{1000 + float32(i)})
+	}
+
+	idx.Build(1, -1)
+
+	result, _ := idx.GetNnsWithinRadius([]float32{0}, 1, -1, idx.CreateContext())
+
+	assert.Len(t, result, 10000)
+}
+
+func TestGetNnsWithinRadiusIsLimitedBySearchK(t *testing.T) {
+	idx := builder.Index[float32, uint32]().EuclideanDistance(1).Build()
+	defer idx.Close()
+
+	for i := 0; i < 1000; i++ {
+		idx.AddItem(uint32(i), []float32{float32(i)})
+	}
+
+	idx.Build(1, -1)
+
+	ctx := idx.CreateContext()
+
+	result, _ := idx.GetNnsWithinRadius([]float32{500}, float32(math.Inf(1)), -1, ctx)
+	assert.Len(t, result, 1000)
+
+	result, _ = idx.GetNnsWithinRadius([]float32{500}, float32(math.Inf(1)), 100, ctx)
+	assert.Less(t, len(result), 1000)
+
+	result, distances := idx.GetNnsWithinRadius([]float32{500}, 2, -1, ctx)
+	assert.Equal(t, uint32(500), result[0])
+	assert.ElementsMatch(t, []uint32{500, 499, 501, 498, 502}, result)
+	assert.Equal(t, []float32{0, 1, 1, 2, 2}, distances)
+}
+
+func TestGetNnsWithinRadiusUnboundedOnSkewedData(t *testing.T) {
+	idx := buildSkewedIndex()
+	defer idx.Close()
+
+	result, _ := idx.GetNnsWithinRadius(
+		[]float32{0}, float32(math.Inf(1)), -1, idx.CreateContext(),
+	)
+
+	assert.Len(t, result, 200)
+}
@@ -0,0 +1,123 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mariotoffia/goannoy/builder"
+	"github.com/mariotoffia/goannoy/index"
+	"github.com/mariotoffia/goannoy/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSaveToWritesSameAsSave(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "saveto.ann")
+
+	idx := builder.Index[float32, uint32]().EuclideanDistance(4).FileHeader().Build()
+	defer idx.Close()
+
+	var buf bytes.Buffer
+
+	assert.ErrorIs(t, idx.SaveTo(&buf), index.ErrNotBuilt)
+
+	addRandomItems(idx, 500, 4)
+	idx.Build(3, -1)
+
+	require.NoError(t, idx.SaveTo(&buf))
+	require.NoError(t, idx.Save(fileName))
+
+	data, err := os.ReadFile(fileName)
+	require.NoError(t, err)
+	assert.Equal(t, data, buf.Bytes())
+
+	loaded := builder.Index[float32, uint32]().EuclideanDistance(4).FileHeader().Build()
+	defer loaded.Close()
+
+	require.NoError(t, loaded.LoadBytes(buf.Bytes()))
+	assert.Equal(t, idx.GetItem(42), loaded.GetItem(42))
+}
+
+func TestSaveReplacesFileAtomically(t *testing.T) {
+	dir := t.TempDir()
+	fileName := filepath.Join(dir, "atomic.ann")
+
+	first := builder.Index[float32, uint32]().EuclideanDistance(1).Build()
+	defer first.Close()
+
+	for i := 0; i < 100; i++ {
+		first.AddItem(uint32(i), []float32{float32(i)})
+	}
+
+	first.Build(2, -1)
+	require.NoError(t, first.Save(fileName))
+
+	// A reader having the file memory mapped
+	reader := builder.Index[float32, uint32]().EuclideanDistance(1).Build()
+	defer reader.Close()
+
+	require.NoError(t, reader.Load(fileName))
+
+	second := builder.Index[float32, uint32]().EuclideanDistance(1).Build()
+	defer second.Close()
+
+	for i := 0; i < 10; i++ {
+		second.AddItem(uint32(i), []float32{float32(-i)})
+	}
+
+	second.Build(2, -1)
+	require.NoError(t, second.Save(fileName))
+
+	// The reader still sees the complete previous file
+	assert.Equal(t, uint32(100), reader.GetNumberOfItems())
+	assert.Equal(t, []float32{99}, reader.GetItem(99))
+
+	require.NoError(t, reader.Load(fileName))
+	assert.Equal(t, uint32(10), reader.GetNumberOfItems())
+	assert.Equal(t, []float32{-9}, reader.GetItem(9))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "no temporary files are left behind")
+}
+
+func TestWriteFileAtomicKeepsFileOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	fileName := filepath.Join(dir, "keep.bin")
+
+	require.NoError(t, os.WriteFile(fileName, []byte("previous"), 0o600))
+
+	failure := errors.New("write failed")
+
+	err := utils.WriteFileAtomic(fileName, func(w io.Writer) error {
+		w.Write([]byte("partial"))
+		return failure
+	})
+
+	assert.ErrorIs(t, err, failure)
+
+	data, err := os.ReadFile(fileName)
+	require.NoError(t, err)
+	assert.Equal(t, "previous", string(data))
+
+	require.NoError(t, utils.WriteFileAtomic(fileName, func(w io.Writer) error {
+		_, err := w.Write([]byte("next"))
+		return err
+	}))
+
+	data, err = os.ReadFile(fileName)
+	require.NoError(t, err)
+	assert.Equal(t, "next", string(data))
+
+	fi, err := os.Stat(fileName)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), fi.Mode().Perm())
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
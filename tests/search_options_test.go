@@ -0,0 +1,58 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/mariotoffia/goannoy/builder"
+	"github.com/mariotoffia/goannoy/interfaces"
+	"github.com/mariotoffia/goannoy/vector"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchOptionsControlsDistances(t *testing.T) {
+	idx := builder.Index[float32, uint32]().AngularDistance(8).Build()
+	defer idx.Close()
+
+	addRandomItems(idx, 500, 8)
+	idx.Build(10, -1)
+
+	ctx := idx.CreateContext()
+	query := idx.GetItem(3)
+
+	expected, normalized := idx.GetNnsByVector(query, 10, -1, ctx)
+	require.Len(t, normalized, 10)
+
+	result, distances := idx.GetNnsByVectorWithOptions(
+		query, 10, -1, ctx, interfaces.SearchOptions[uint32]{OmitDistances: true},
+	)
+
+	assert.Equal(t, expected, result)
+	assert.Nil(t, distances)
+
+	result, raw := idx.GetNnsByVectorWithOptions(
+		query, 10, -1, ctx, interfaces.SearchOptions[uint32]{RawDistances: true},
+	)
+
+	assert.Equal(t, expected, result)
+
+	for i := range result {
+		assert.InDelta(t, normalized[i]*normalized[i], raw[i], 1e-5)
+		assert.InDelta(t, idx.GetRawDistance(3, result[i]), raw[i], 1e-5)
+	}
+
+	// Normalize the query up front
+	unit := make([]float32, len(query))
+	norm := vector.GetNorm(query, uint32(len(query)))
+
+	for i := range query {
+		unit[i] = query[i] / norm
+	}
+
+	result, distances = idx.GetNnsByVectorWithOptions(
+		unit, 10, -1, ctx, interfaces.SearchOptions[uint32]{QueryNormalized: true},
+	)
+
+	assert.Equal(t, expected, result)
+	assert.InDeltaSlice(t, normalized, distances, 1e-5)
+}
@@ -0,0 +1,286 @@
+package tests
+
+import (
+	"context"
+	"math"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/mariotoffia/goannoy/builder"
+	"github.com/mariotoffia/goannoy/distance/dotproduct"
+	"github.com/mariotoffia/goannoy/distance/euclidean"
+	"github.com/mariotoffia/goannoy/index/sharded"
+	"github.com/mariotoffia/goannoy/interfaces"
+	"github.com/mariotoffia/goannoy/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newShardedIndex(numShards int, partition sharded.Partition[uint32]) *sharded.ShardedIndex[float32, uint32] {
+	return sharded.New(
+		euclidean.Distance[float32, uint32](4),
+		numShards,
+		func(int) interfaces.AnnoyIndex[float32, uint32] {
+			return builder.Index[float32, uint32]().EuclideanDistance(4).Build()
+		},
+		partition,
+	)
+}
+
+func ingestRandomItems(t *testing.T, idx *sharded.ShardedIndex[float32, uint32], numItems int) {
+	items := make(chan sharded.Item[float32, uint32])
+
+	go func() {
+		defer close(items)
+
+		rnd := random.NewGoRandom()
+
+		for i := 0; i < numItems; i++ {
+			v := make([]float32, 4)
+
+			for j := range v {
+				v[j] = float32(rnd.NormFloat64())
+			}
+
+			items <- sharded.Item[float32, uint32]{Index: uint32(i), Vector: v}
+		}
+	}()
+
+	require.NoError(t, idx.Ingest(context.Background(), items))
+}
+
+func TestShardedPartitions(t *testing.T) {
+	hash := sharded.HashPartition[uint32]()
+	counts := make([]int, 4)
+
+	for i := uint32(0); i < 4000; i++ {
+		counts[hash(i, 4)]++
+	}
+
+	for _, cnt := range counts {
+		assert.InDelta(t, 1000, cnt, 200)
+	}
+
+	rng := sharded.RangePartition[uint32](100, 200)
+
+	assert.Equal(t, 0, rng(99, 3))
+	assert.Equal(t, 1, rng(100, 3))
+	assert.Equal(t, 2, rng(200, 3))
+	assert.Equal(t, 1, rng(200, 2))
+}
+
+func TestShardedIndexMergesShards(t *testing.T) {
+	idx := newShardedIndex(3, sharded.HashPartition[uint32]())
+	defer idx.Close()
+
+	ingestRandomItems(t, idx, 3000)
+
+	assert.Equal(t, uint32(3000), idx.GetNumberOfItems())
+
+	for _, shard := range idx.Shards() {
+		assert.NotZero(t, shard.GetNumberOfItems())
+	}
+
+	var last interfaces.BuildProgress
+
+	require.NoError(t, idx.BuildContext(context.Background(), interfaces.BuildOptions{
+		NumberOfTrees: 4,
+		NumWorkers:    -1,
+		Progress:      func(p interfaces.BuildProgress) { last = p },
+	}))
+
+	assert.Equal(t, 12, last.TreesBuilt)
+	assert.Equal(t, 12, last.NumberOfTrees)
+	assert.Equal(t, 4, idx.GetNumberOfTrees())
+
+	query := idx.GetItem(42)
+	radius := float32(0.8)
+
+	// Brute force over all shards
+	var expected []uint32
+
+	for i := uint32(0); i < 3000; i++ {
+		if idx.GetDistance(42, i) <= radius {
+			expected = append(expected, i)
+		}
+	}
+
+	ctx := idx.CreateContext()
+
+	result, distances := idx.GetNnsWithinRadius(query, radius, -1, ctx)
+
+	assert.ElementsMatch(t, expected, result)
+	assert.IsNonDecreasing(t, distances)
+
+	result, distances = idx.GetNnsByVector(query, 10, -1, ctx)
+
+	require.Len(t, result, 10)
+	assert.IsNonDecreasing(t, distances)
+
+	// The merged result is the best of the results of each shard
+	var best []float32
+
+	for _, shard := range idx.Shards() {
+		_, shardDistances := shard.GetNnsByVector(query, 10, -1, shard.CreateContext())
+		best = append(best, shardDistances...)
+	}
+
+	sort.Slice(best, func(i, j int) bool { return best[i] < best[j] })
+	assert.Equal(t, best[:10], distances)
+
+	for i, item := range result {
+		assert.InDelta(t, idx.GetDistance(42, item), distances[i], 1e-5)
+	}
+
+	result, _ = idx.GetNnsByVectorWithOptions(query, 10, -1, ctx, interfaces.SearchOptions[uint32]{
+		Filter: func(item uint32) bool { return item%2 == 1 },
+	})
+
+	require.NotEmpty(t, result)
+
+	for _, item := range result {
+		assert.Equal(t, uint32(1), item%2)
+	}
+}
+
+func TestShardedIndexSaveAndLoad(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "sharded.ann")
+
+	idx := newShardedIndex(2, sharded.RangePartition[uint32](500))
+	defer idx.Close()
+
+	ingestRandomItems(t, idx, 1000)
+
+	idx.Build(3, -1)
+
+	require.NoError(t, idx.Save(fileName))
+	assert.FileExists(t, sharded.ShardFileName(fileName, 0))
+	assert.FileExists(t, sharded.ShardFileName(fileName, 1))
+
+	loaded := newShardedIndex(2, sharded.RangePartition[uint32](500))
+	defer loaded.Close()
+
+	require.NoError(t, loaded.Load(fileName))
+
+	assert.Equal(t, uint32(1000), loaded.GetNumberOfItems())
+	assert.Equal(t, idx.GetItem(700), loaded.GetItem(700))
+	assert.Equal(t, uint32(500), loaded.Shards()[1].GetNumberOfItems())
+
+	expected, _ := idx.GetNnsByItem(700, 10, -1, idx.CreateContext())
+	result, _ := loaded.GetNnsByItem(700, 10, -1, loaded.CreateContext())
+	assert.Equal(t, expected, result)
+
+	mismatch := newShardedIndex(3, sharded.HashPartition[uint32]())
+	defer mismatch.Close()
+
+	assert.Error(t, mismatch.Load(fileName))
+}
+
+func TestShardedIndexMergesByRawDistance(t *testing.T) {
+	idx := sharded.New(
+		dotproduct.Distance[float32, uint32](1),
+		3,
+		func(int) interfaces.AnnoyIndex[float32, uint32] {
+			return builder.Index[float32, uint32]().DotProductDistance(1).Build()
+		},
+		sharded.RangePartition[uint32](34, 67),
+	)
+
+	defer idx.Close()
+
+	for i := 0; i < 100; i++ {
+		idx.AddItem(uint32(i), []float32{float32(i)})
+	}
+
+	idx.Build(3, -1)
+
+	// Largest dot product first, the normalized distances are hence descending
+	result, distances := idx.GetNnsByVector([]float32{1}, 3, 1000, idx.CreateContext())
+
+	assert.Equal(t, []uint32{99, 98, 97}, result)
+	assert.Equal(t, []float32{99, 98, 97}, distances)
+
+	result, distances = idx.GetNnsByVectorWithOptions(
+		[]float32{1}, 3, 1000, idx.CreateContext(),
+		interfaces.SearchOptions[uint32]{RawDistances: true},
+	)
+
+	assert.Equal(t, []uint32{99, 98, 97}, result)
+	assert.Equal(t, []float32{-99, -98, -97}, distances)
+}
+
+func TestShardedIndexUnknownItems(t *testing.T) {
+	idx := newShardedIndex(2, sharded.HashPartition[uint32]())
+	defer idx.Close()
+
+	require.NoError(t, idx.AddItemE(0, []float32{1, 2, 3, 4}))
+
+	// A failed add is not recorded and the next item gets the local slot
+	assert.Error(t, idx.AddItemE(1, []float32{1, 2}))
+	assert.Equal(t, uint32(1), idx.GetNumberOfItems())
+	assert.Nil(t, idx.GetItem(1))
+
+	assert.True(t, math.IsNaN(float64(idx.GetRawDistance(0, 1))))
+	assert.True(t, math.IsNaN(float64(idx.GetDistance(1, 0))))
+	assert.Error(t, idx.MarkDeleted(1))
+	assert.False(t, idx.IsDeleted(1))
+}
+
+func TestShardedIngestRecordsOnlyAddedItems(t *testing.T) {
+	idx := newShardedIndex(2, sharded.HashPartition[uint32]())
+	defer idx.Close()
+
+	items := make(chan sharded.Item[float32, uint32], 3)
+	items <- sharded.Item[float32, uint32]{Index: 0, Vector: []float32{1, 2, 3, 4}}
+	items <- sharded.Item[float32, uint32]{Index: 1, Vector: []float32{1, 2}}
+	items <- sharded.Item[float32, uint32]{Index: 2, Vector: []float32{5, 6, 7, 8}}
+	close(items)
+
+	assert.Error(t, idx.Ingest(context.Background(), items))
+	assert.Equal(t, uint32(2), idx.GetNumberOfItems())
+	assert.Nil(t, idx.GetItem(1))
+	assert.Equal(t, []float32{5, 6, 7, 8}, idx.GetItem(2))
+}
+
+func TestShardedConcurrentIngestAndReads(t *testing.T) {
+	idx := newShardedIndex(3, sharded.HashPartition[uint32]())
+	defer idx.Close()
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		ingestRandomItems(t, idx, 2000)
+	}()
+
+	for {
+		select {
+		case <-done:
+			assert.Equal(t, uint32(2000), idx.GetNumberOfItems())
+			return
+		default:
+			n := idx.GetNumberOfItems()
+			idx.GetItem(n / 2)
+			idx.IsDeleted(n / 2)
+			idx.GetRawDistance(0, n/2)
+		}
+	}
+}
+
+func TestShardedCompactSkipsShardsWithNothingToCompact(t *testing.T) {
+	idx := newShardedIndex(2, sharded.HashPartition[uint32]())
+	defer idx.Close()
+
+	ingestRandomItems(t, idx, 100)
+	idx.Build(3, -1)
+
+	// Only the shard of item 0 has deleted items
+	require.NoError(t, idx.MarkDeleted(0))
+	require.NoError(t, idx.Compact())
+	assert.Equal(t, 0, idx.DeletedCount())
+
+	result, _ := idx.GetNnsByVector(idx.GetItem(1), 100, -1, idx.CreateContext())
+	assert.Len(t, result, 99)
+	assert.NotContains(t, result, uint32(0))
+}
@@ -0,0 +1,46 @@
+package tests
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mariotoffia/goannoy/builder"
+	"github.com/mariotoffia/goannoy/index"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnbuildAndRebuildWithMoreItems(t *testing.T) {
+	idx := builder.Index[float32, uint32]().EuclideanDistance(2).Build()
+	defer idx.Close()
+
+	idx.AddItem(0, []float32{0, 0})
+	idx.AddItem(1, []float32{1, 0})
+	idx.AddItem(2, []float32{2, 0})
+	require.NoError(t, idx.BuildE(2, -1))
+
+	result, _ := idx.GetNnsByVector([]float32{9, 0}, 1, -1, idx.CreateContext())
+	assert.Equal(t, []uint32{2}, result)
+
+	require.NoError(t, idx.Unbuild())
+
+	idx.AddItem(3, []float32{10, 0})
+	require.NoError(t, idx.BuildE(5, -1))
+
+	result, _ = idx.GetNnsByVector([]float32{9, 0}, 2, -1, idx.CreateContext())
+	assert.Equal(t, []uint32{3, 2}, result)
+
+	fileName := filepath.Join(t.TempDir(), "rebuild.ann")
+	require.NoError(t, idx.Save(fileName))
+
+	stat, err := os.Stat(fileName)
+	require.NoError(t, err)
+
+	// 4 items, 5 roots and 5 root copies, each node is 24 bytes
+	assert.Equal(t, int64(14*24), stat.Size())
+
+	err = idx.Unbuild()
+	assert.True(t, errors.Is(err, index.ErrIndexLoaded), "%v", err)
+}
@@ -0,0 +1,101 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/mariotoffia/goannoy/builder"
+	"github.com/mariotoffia/goannoy/interfaces"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func buildZeroAllocIndex() interfaces.AnnoyIndex[float32, uint32] {
+	idx := builder.Index[float32, uint32]().AngularDistance(32).Build()
+
+	addRandomItems(idx, 10000, 32)
+	idx.Build(10, -1)
+
+	return idx
+}
+
+func TestGetNnsByVectorIntoDoNotAllocate(t *testing.T) {
+	idx := buildZeroAllocIndex()
+	defer idx.Close()
+
+	ctx := idx.CreateContext()
+	query := idx.GetItem(42)
+
+	result := make([]uint32, 10)
+	distances := make([]float32, 10)
+
+	expected, expectedDistances := idx.GetNnsByVector(query, 10, -1, ctx)
+
+	n := idx.GetNnsByVectorInto(query, -1, result, distances, ctx)
+	assert.Equal(t, expected, result[:n])
+	assert.Equal(t, expectedDistances, distances[:n])
+
+	allocs := testing.AllocsPerRun(100, func() {
+		idx.GetNnsByVectorInto(query, -1, result, distances, ctx)
+	})
+
+	assert.Zero(t, allocs)
+}
+
+func BenchmarkGetNnsByVectorInto(b *testing.B) {
+	idx := buildZeroAllocIndex()
+	defer idx.Close()
+
+	ctx := idx.CreateContext()
+	query := idx.GetItem(42)
+
+	result := make([]uint32, 10)
+	distances := make([]float32, 10)
+
+	b.Run("Into", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			idx.GetNnsByVectorInto(query, -1, result, distances, ctx)
+		}
+	})
+
+	b.Run("Allocating", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			idx.GetNnsByVector(query, 10, -1, ctx)
+		}
+	})
+}
+
+func TestGetNnsByVectorIntoDoNotAllocateWithDeletedItems(t *testing.T) {
+	idx := buildZeroAllocIndex()
+	defer idx.Close()
+
+	require.NoError(t, idx.MarkDeleted(7))
+
+	ctx := idx.CreateContext()
+	query := idx.GetItem(42)
+
+	result := make([]uint32, 10)
+	distances := make([]float32, 10)
+
+	idx.GetNnsByVectorInto(query, -1, result, distances, ctx)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		idx.GetNnsByVectorInto(query, -1, result, distances, ctx)
+	})
+
+	assert.Zero(t, allocs)
+}
+
+func TestGetNnsByVectorIntoLimitsToDistances(t *testing.T) {
+	idx := buildZeroAllocIndex()
+	defer idx.Close()
+
+	result := make([]uint32, 10)
+	distances := make([]float32, 3)
+
+	n := idx.GetNnsByVectorInto(idx.GetItem(42), -1, result, distances, idx.CreateContext())
+	assert.Equal(t, 3, n)
+}
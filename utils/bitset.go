@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"encoding/binary"
+	"io"
+	"math/bits"
+
+	"github.com/mariotoffia/goannoy/interfaces"
+)
+
+// Bitset is a set of item indexes backed by a bitmap. The zero value is an empty set.
+type Bitset[TIX interfaces.IndexTypes] struct {
+	words []uint64
+}
+
+// NewBitset creates a new `Bitset` with room for _size_ items without re-allocation.
+func NewBitset[TIX interfaces.IndexTypes](size TIX) *Bitset[TIX] {
+	return &Bitset[TIX]{words: make([]uint64, (uint64(size)+63)/64)}
+}
+
+// Set adds the _item_ to the set.
+func (b *Bitset[TIX]) Set(item TIX) {
+	w := int(uint64(item) / 64)
+
+	if w >= len(b.words) {
+		words := make([]uint64, w+1)
+		copy(words, b.words)
+		b.words = words
+	}
+
+	b.words[w] |= 1 << (uint64(item) % 64)
+}
+
+// Clear removes the _item_ from the set.
+func (b *Bitset[TIX]) Clear(item TIX) {
+	if w := int(uint64(item) / 64); w < len(b.words) {
+		b.words[w] &^= 1 << (uint64(item) % 64)
+	}
+}
+
+// Contains returns `true` if the _item_ is in the set.
+func (b *Bitset[TIX]) Contains(item TIX) bool {
+	w := int(uint64(item) / 64)
+
+	return w < len(b.words) && b.words[w]&(1<<(uint64(item)%64)) != 0
+}
+
+// Count returns the number of items in the set.
+func (b *Bitset[TIX]) Count() int {
+	cnt := 0
+
+	for _, w := range b.words {
+		cnt += bits.OnesCount64(w)
+	}
+
+	return cnt
+}
+
+// WriteTo writes the set to _w_ as the number of words followed by the words, all as
+// little endian `uint64`.
+func (b *Bitset[TIX]) WriteTo(w io.Writer) (int64, error) {
+	if err := binary.Write(w, binary.LittleEndian, uint64(len(b.words))); err != nil {
+		return 0, err
+	}
+
+	if err := binary.Write(w, binary.LittleEndian, b.words); err != nil {
+		return 8, err
+	}
+
+	return int64(8 + 8*len(b.words)), nil
+}
+
+// ReadFrom replaces the set with the one read from _r_ as written by `WriteTo`.
+func (b *Bitset[TIX]) ReadFrom(r io.Reader) (int64, error) {
+	return b.ReadFromLimited(r, ^TIX(0))
+}
+
+// ReadFromLimited is same as `ReadFrom` but only keeps room for _size_ items. Hence, a corrupt
+// word count never allocates more than needed for _size_ items and items above are dropped.
+func (b *Bitset[TIX]) ReadFromLimited(r io.Reader, size TIX) (int64, error) {
+	var n uint64
+
+	if err := binary.Read(r, binary.LittleEndian, &n); err != nil {
+		return 0, err
+	}
+
+	keep := min(n, uint64(size)/64+1)
+	words := make([]uint64, keep)
+
+	if err := binary.Read(r, binary.LittleEndian, words); err != nil {
+		return 8, err
+	}
+
+	read := int64(8 + 8*keep)
+
+	for skip := n - keep; skip > 0; skip-- {
+		var word uint64
+
+		if err := binary.Read(r, binary.LittleEndian, &word); err != nil {
+			return read, err
+		}
+
+		read += 8
+	}
+
+	b.words = words
+
+	return read, nil
+}
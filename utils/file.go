@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// WriteFileAtomic writes _fileName_ using _write_ to a temporary file in the same directory,
+// syncs it to disk and renames it to _fileName_. Hence, readers, e.g. memory mapping the file,
+// either see the previous or the new file but never a partially written file.
+//
+// If _write_ fails, the temporary file is removed and _fileName_ is left untouched.
+func WriteFileAtomic(fileName string, write func(w io.Writer) error) (err error) {
+	dir, base := filepath.Split(fileName)
+
+	if dir == "" {
+		dir = "."
+	}
+
+	file, err := os.CreateTemp(dir, base+".tmp*")
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			file.Close()
+			os.Remove(file.Name())
+		}
+	}()
+
+	w := bufio.NewWriter(file)
+
+	if err = write(w); err != nil {
+		return err
+	}
+
+	if err = w.Flush(); err != nil {
+		return err
+	}
+
+	if err = file.Sync(); err != nil {
+		return err
+	}
+
+	if err = file.Close(); err != nil {
+		return err
+	}
+
+	// Temporary files are only readable by the owner, use the mode of the replaced file
+	mode := os.FileMode(0o644)
+
+	if fi, statErr := os.Stat(fileName); statErr == nil {
+		mode = fi.Mode().Perm()
+	}
+
+	if err = os.Chmod(file.Name(), mode); err != nil {
+		return err
+	}
+
+	if err = os.Rename(file.Name(), fileName); err != nil {
+		return err
+	}
+
+	syncDir(dir)
+
+	return nil
+}
+
+// syncDir syncs the directory so that a rename is persisted. It is best effort since not
+// all platforms support syncing directories.
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
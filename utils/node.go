@@ -19,7 +19,8 @@ func GetPtr[TV interfaces.VectorType, TIX interfaces.IndexTypes](
 }
 
 // CopyNode copies the source node to the destination node. Note that the destination node
-// must be of the same type and take up the same amount of memory as the source node.
+// must be of the same type and take up the same amount of memory as the source node. The
+// _size_ is the node size in bytes (not the vector length).
 func CopyNode[TV interfaces.VectorType, TIX interfaces.IndexTypes](
 	dst, src interfaces.Node[TV, TIX], size TIX,
 ) {
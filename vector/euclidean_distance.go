@@ -1,6 +1,10 @@
 package vector
 
-import "github.com/mariotoffia/goannoy/interfaces"
+import (
+	"unsafe"
+
+	"github.com/mariotoffia/goannoy/interfaces"
+)
 
 func EuclideanDistance[TV interfaces.VectorType, TIX interfaces.IndexTypes](
 	a, b []TV, vectorLength TIX,
@@ -11,3 +15,21 @@ func EuclideanDistance[TV interfaces.VectorType, TIX interfaces.IndexTypes](
 	}
 	return sum
 }
+
+func EuclideanDistanceUnsafe[TV interfaces.VectorType, TIX interfaces.IndexTypes](
+	a, b *TV, vectorLength TIX,
+) TV {
+	a_ptr := unsafe.Pointer(a)
+	b_ptr := unsafe.Pointer(b)
+	size := TIX(unsafe.Sizeof(TV(0)))
+
+	var sum TV
+
+	for i := TIX(0); i < vectorLength; i++ {
+		a := *(*TV)(unsafe.Pointer(unsafe.Add(a_ptr, i*size)))
+		b := *(*TV)(unsafe.Pointer(unsafe.Add(b_ptr, i*size)))
+		sum += (a - b) * (a - b)
+	}
+
+	return sum
+}